@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bytes"
+	"mime"
+)
+
+// AudioFormat describes the container of a media file in terms ffmpeg understands
+type AudioFormat struct {
+	// Name is the ffmpeg demuxer that reads the format
+	Name string
+	// Streamable formats can be transcoded as they are read, the others must be
+	// read in full first because their index may be at the end of the file
+	Streamable bool
+}
+
+var (
+	AudioFormatAAC      = AudioFormat{Name: "aac", Streamable: true}
+	AudioFormatAIFF     = AudioFormat{Name: "aiff", Streamable: true}
+	AudioFormatFLAC     = AudioFormat{Name: "flac", Streamable: true}
+	AudioFormatMatroska = AudioFormat{Name: "matroska", Streamable: true}
+	AudioFormatMP3      = AudioFormat{Name: "mp3", Streamable: true}
+	AudioFormatMP4      = AudioFormat{Name: "mp4", Streamable: false}
+	AudioFormatOgg      = AudioFormat{Name: "ogg", Streamable: true}
+	AudioFormatWAV      = AudioFormat{Name: "wav", Streamable: true}
+)
+
+var audioFormatsByContentType = map[string]AudioFormat{
+	"audio/aac":           AudioFormatAAC,
+	"audio/aacp":          AudioFormatAAC,
+	"audio/aiff":          AudioFormatAIFF,
+	"audio/x-aiff":        AudioFormatAIFF,
+	"audio/flac":          AudioFormatFLAC,
+	"audio/x-flac":        AudioFormatFLAC,
+	"audio/webm":          AudioFormatMatroska,
+	"video/webm":          AudioFormatMatroska,
+	"audio/x-matroska":    AudioFormatMatroska,
+	"video/x-matroska":    AudioFormatMatroska,
+	"audio/mpeg":          AudioFormatMP3,
+	"audio/mp3":           AudioFormatMP3,
+	"audio/mp4":           AudioFormatMP4,
+	"audio/x-m4a":         AudioFormatMP4,
+	"video/mp4":           AudioFormatMP4,
+	"audio/ogg":           AudioFormatOgg,
+	"audio/opus":          AudioFormatOgg,
+	"application/ogg":     AudioFormatOgg,
+	"audio/wav":           AudioFormatWAV,
+	"audio/wave":          AudioFormatWAV,
+	"audio/x-wav":         AudioFormatWAV,
+	"audio/vnd.wave":      AudioFormatWAV,
+	"audio/x-pn-wav":      AudioFormatWAV,
+	"audio/vnd.dlna.adts": AudioFormatAAC,
+}
+
+// DetectAudioFormat determines the format of a media file from its first bytes, falling back to its content type
+//
+// Magic bytes win over the content type because servers often label files
+// generically, for example as application/octet-stream.
+func DetectAudioFormat(contentType string, head []byte) (AudioFormat, bool) {
+	if v, ok := sniffAudioFormat(head); ok {
+		return v, true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return AudioFormat{}, false
+	}
+
+	v, ok := audioFormatsByContentType[mediaType]
+	return v, ok
+}
+
+func sniffAudioFormat(head []byte) (AudioFormat, bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return AudioFormatMP3, true
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return AudioFormatOgg, true
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return AudioFormatFLAC, true
+	case bytes.HasPrefix(head, []byte("\x1a\x45\xdf\xa3")):
+		return AudioFormatMatroska, true
+	case len(head) >= 12 && bytes.HasPrefix(head, []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE")):
+		return AudioFormatWAV, true
+	case len(head) >= 12 && bytes.HasPrefix(head, []byte("FORM")) && (bytes.Equal(head[8:12], []byte("AIFF")) || bytes.Equal(head[8:12], []byte("AIFC"))):
+		return AudioFormatAIFF, true
+	case len(head) >= 8 && bytes.Equal(head[4:8], []byte("ftyp")):
+		return AudioFormatMP4, true
+	case len(head) >= 2 && head[0] == 0xff && head[1]&0xf6 == 0xf0:
+		// adts frame sync, layer is always zero
+		return AudioFormatAAC, true
+	case len(head) >= 2 && head[0] == 0xff && head[1]&0xe0 == 0xe0:
+		// mpeg audio frame sync
+		return AudioFormatMP3, true
+	}
+
+	return AudioFormat{}, false
+}
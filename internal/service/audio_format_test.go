@@ -0,0 +1,50 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDetectAudioFormat(t *testing.T) {
+	Convey("magic bytes identify the format even when the content type is generic", t, func() {
+		cases := []struct {
+			head   string
+			format service.AudioFormat
+		}{
+			{"ID3\x04\x00", service.AudioFormatMP3},
+			{"\xff\xfb\x90\x64", service.AudioFormatMP3},
+			{"\xff\xf1\x50\x80", service.AudioFormatAAC},
+			{"OggS\x00\x02", service.AudioFormatOgg},
+			{"fLaC\x00\x00\x00\x22", service.AudioFormatFLAC},
+			{"RIFF\x24\x08\x00\x00WAVEfmt ", service.AudioFormatWAV},
+			{"\x00\x00\x00\x20ftypM4A ", service.AudioFormatMP4},
+			{"\x1a\x45\xdf\xa3\x9f\x42", service.AudioFormatMatroska},
+		}
+
+		for _, c := range cases {
+			v, ok := service.DetectAudioFormat("application/octet-stream", []byte(c.head))
+			So(ok, ShouldBeTrue)
+			So(v, ShouldResemble, c.format)
+		}
+	})
+
+	Convey("the content type is used when the magic bytes are not recognized", t, func() {
+		v, ok := service.DetectAudioFormat("audio/ogg; codecs=opus", []byte("????"))
+		So(ok, ShouldBeTrue)
+		So(v, ShouldResemble, service.AudioFormatOgg)
+
+		v, ok = service.DetectAudioFormat("Audio/MP4", nil)
+		So(ok, ShouldBeTrue)
+		So(v.Streamable, ShouldBeFalse)
+	})
+
+	Convey("unknown formats are not detected", t, func() {
+		_, ok := service.DetectAudioFormat("text/html; charset=utf-8", []byte("<!DOCTYPE html>"))
+		So(ok, ShouldBeFalse)
+
+		_, ok = service.DetectAudioFormat("", nil)
+		So(ok, ShouldBeFalse)
+	})
+}
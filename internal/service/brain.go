@@ -88,14 +88,24 @@ func (m *SyncMap[K, V]) Store(key K, value V) {
 type Brain struct {
 	mutex            sync.Mutex
 	playersByGuildID SyncMap[string, *Player]
+	transcodeManager *TranscodeManager
+	searcher         Searcher
+	library          *Library
 }
 
-func NewBrain() *Brain {
+func NewBrain(tm *TranscodeManager, sr Searcher, lib *Library) *Brain {
 	return &Brain{
 		playersByGuildID: SyncMap[string, *Player]{},
+		transcodeManager: tm,
+		searcher:         sr,
+		library:          lib,
 	}
 }
 
+func (b *Brain) TranscodeManager() *TranscodeManager {
+	return b.transcodeManager
+}
+
 func (b *Brain) Player(ctx context.Context, wg *sync.WaitGroup, s *discordgo.Session, guildId string) *Player {
 
 	result, ok := b.playersByGuildID.Load(guildId)
@@ -115,7 +125,7 @@ func (b *Brain) Player(ctx context.Context, wg *sync.WaitGroup, s *discordgo.Ses
 		return result
 	}
 
-	result = NewPlayer(ctx, wg, s, guildId)
+	result = NewPlayer(ctx, wg, s, guildId, b.transcodeManager, b.searcher, b.library)
 
 	b.playersByGuildID.Store(guildId, result)
 
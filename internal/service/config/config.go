@@ -6,13 +6,21 @@ import (
 )
 
 type Config struct {
-	DiscordBotToken string `split_words:"true" required:"true"`
+	DiscordBotToken    string `split_words:"true" required:"true"`
+	TranscodeWorkers   int    `split_words:"true" default:"1"`
+	TranscodeQueueSize int    `split_words:"true" default:"128"`
+	// LibraryDir is a directory of audio files to index and play, the library is disabled when empty
+	LibraryDir string `split_words:"true"`
 }
 
 func (c *Config) Validate() error {
 	return validation.ValidateStruct(c,
 		// DiscordBotToken must not be empty
 		validation.Field(&c.DiscordBotToken, validation.Required),
+		// TranscodeWorkers must be at least one
+		validation.Field(&c.TranscodeWorkers, validation.Min(1)),
+		// TranscodeQueueSize must be at least one
+		validation.Field(&c.TranscodeQueueSize, validation.Min(1)),
 	)
 }
 
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ControlsCustomIDPrefix prefixes the custom ids of the buttons on a controls message
+const ControlsCustomIDPrefix = "controls:"
+
+// actions of the buttons on a controls message
+const (
+	ControlsActionPrevious = "previous"
+	ControlsActionPause    = "pause"
+	ControlsActionResume   = "resume"
+	ControlsActionNext     = "next"
+	ControlsActionStop     = "stop"
+	ControlsActionRepeat   = "repeat"
+	ControlsActionShuffle  = "shuffle"
+)
+
+// controlsRefreshDelay groups bursts of player changes into one message edit
+const controlsRefreshDelay = 500 * time.Millisecond
+
+const controlsEmbedColor = 0x5865f2
+
+// controlsMaxTitleLen is the most runes of a track title shown on a controls message
+const controlsMaxTitleLen = 200
+
+// controlsMessage identifies the guild's interactive now playing message
+type controlsMessage struct {
+	channelID string
+	messageID string
+}
+
+// notifyChanged requests the controls message be refreshed
+//
+// Never blocks.
+func (p *Player) notifyChanged() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// ShowControls sends the guild's interactive now playing message to a channel
+//
+// The message is edited in place as the player changes. Any previous controls
+// message of the guild is deleted so there is only ever one.
+func (p *Player) ShowControls(channelID string) error {
+	embed, components := p.renderControls()
+
+	msg, err := p.discordSession.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		return err
+	}
+
+	old := p.controls.Swap(&controlsMessage{
+		channelID: msg.ChannelID,
+		messageID: msg.ID,
+	})
+
+	if old != nil {
+		if err := p.discordSession.ChannelMessageDelete(old.channelID, old.messageID); err != nil {
+			slog.Warn(
+				"failed to delete old controls message",
+				"error", err,
+				"guild_id", p.discordGuildId,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (p *Player) controlsGoroutine(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ctxDone := ctx.Done()
+
+	for {
+		select {
+		case <-ctxDone:
+			return
+		case <-p.changed:
+		}
+
+		select {
+		case <-ctxDone:
+			return
+		case <-time.After(controlsRefreshDelay):
+		}
+
+		p.refreshControls()
+	}
+}
+
+func (p *Player) refreshControls() {
+	c := p.controls.Load()
+	if c == nil {
+		return
+	}
+
+	embed, components := p.renderControls()
+	embeds := []*discordgo.MessageEmbed{embed}
+
+	_, err := p.discordSession.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         c.messageID,
+		Channel:    c.channelID,
+		Embeds:     embeds,
+		Components: components,
+	})
+	if err == nil {
+		return
+	}
+
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound {
+		// someone deleted the message, stop maintaining it
+		p.controls.CompareAndSwap(c, nil)
+		return
+	}
+
+	slog.Error(
+		"failed to update controls message",
+		"error", err,
+		"guild_id", p.discordGuildId,
+	)
+}
+
+func (p *Player) renderControls() (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	np, playing := p.NowPlaying()
+	repeatMode := p.RepeatMode()
+	shuffling := p.Shuffling()
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "now playing",
+		Description: "nothing is playing",
+		Color:       controlsEmbedColor,
+	}
+
+	if playing {
+		t := &np.Track
+		meta := t.Metadata()
+
+		embed.Description = "[" + t.LinkText(controlsMaxTitleLen) + "](" + t.SrcUrlStr() + ")"
+		if t.AuthorMention != "" {
+			embed.Description += "\nadded by " + t.AuthorMention
+		}
+
+		if meta.ThumbnailURL != "" {
+			embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: meta.ThumbnailURL}
+		}
+
+		if meta.Channel != "" {
+			embed.Author = &discordgo.MessageEmbedAuthor{Name: meta.Channel}
+		}
+
+		if d, ok := t.DurationString(); ok {
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name:   "duration",
+				Value:  d,
+				Inline: true,
+			})
+		}
+	}
+
+	state := "stopped"
+	if playing {
+		state = "playing"
+		if np.Paused {
+			state = "paused"
+		}
+	}
+
+	shuffle := "off"
+	if shuffling {
+		shuffle = "on"
+	}
+
+	embed.Fields = append(embed.Fields,
+		&discordgo.MessageEmbedField{Name: "state", Value: state, Inline: true},
+		&discordgo.MessageEmbedField{Name: "repeat", Value: repeatMode.String(), Inline: true},
+		&discordgo.MessageEmbedField{Name: "shuffle", Value: shuffle, Inline: true},
+	)
+
+	playPause := discordgo.Button{
+		Label:    "pause",
+		Style:    discordgo.PrimaryButton,
+		CustomID: ControlsCustomIDPrefix + ControlsActionPause,
+	}
+	if !playing || np.Paused {
+		playPause.Label = "resume"
+		playPause.CustomID = ControlsCustomIDPrefix + ControlsActionResume
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "previous",
+					Style:    discordgo.SecondaryButton,
+					CustomID: ControlsCustomIDPrefix + ControlsActionPrevious,
+				},
+				playPause,
+				discordgo.Button{
+					Label:    "skip",
+					Style:    discordgo.SecondaryButton,
+					CustomID: ControlsCustomIDPrefix + ControlsActionNext,
+				},
+				discordgo.Button{
+					Label:    "stop",
+					Style:    discordgo.DangerButton,
+					CustomID: ControlsCustomIDPrefix + ControlsActionStop,
+				},
+			},
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "repeat: " + repeatMode.String(),
+					Style:    discordgo.SecondaryButton,
+					CustomID: ControlsCustomIDPrefix + ControlsActionRepeat,
+				},
+				discordgo.Button{
+					Label:    "shuffle: " + shuffle,
+					Style:    discordgo.SecondaryButton,
+					CustomID: ControlsCustomIDPrefix + ControlsActionShuffle,
+				},
+			},
+		},
+	}
+
+	return embed, components
+}
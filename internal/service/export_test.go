@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// testStreamer is a track that is only ever identified by its url
+type testStreamer struct {
+	AudioStreamer
+	url string
+}
+
+func (ts testStreamer) SrcUrlStr() string {
+	return ts.url
+}
+
+func newTestTrack(url string) Track {
+	return Track{
+		AudioStreamer: testStreamer{url: url},
+		handle:        newTrackHandle(),
+	}
+}
+
+// NewPlaylistTestPlayer returns a player holding a playlist of the given urls
+//
+// No goroutines are started, so only the playlist bookkeeping of the player can be used.
+func NewPlaylistTestPlayer(urls ...string) *Player {
+	m := PlayerMemory{
+		currentTrackIdx: -1,
+	}
+
+	for _, url := range urls {
+		m.tracks = append(m.tracks, newTestTrack(url))
+	}
+
+	p := &Player{
+		wg:          &sync.WaitGroup{},
+		changed:     make(chan struct{}, 1),
+		cancelFuncs: map[*func(error)]struct{}{},
+	}
+	p.memory.Store(m)
+
+	return p
+}
+
+// SetTestPosition sets the current track and if the track after it is pinned to play next
+func (p *Player) SetTestPosition(currentTrackIdx int, pinnedNext bool) {
+	p.withMemory(func(m *PlayerMemory) {
+		m.currentTrackIdx = currentTrackIdx
+		m.pinnedNext = pinnedNext
+	})
+}
+
+// TestPosition returns the urls of the playlist, the current track and if the track after it is pinned to play next
+func (p *Player) TestPosition() ([]string, int, bool) {
+	var urls []string
+	var currentTrackIdx int
+	var pinnedNext bool
+
+	p.withMemory(func(m *PlayerMemory) {
+		for _, t := range m.tracks {
+			urls = append(urls, t.SrcUrlStr())
+		}
+
+		currentTrackIdx = m.currentTrackIdx
+		pinnedNext = m.pinnedNext
+	})
+
+	return urls, currentTrackIdx, pinnedNext
+}
+
+// PlayTestTracks adds tracks to the playlist the way a single play request does while the player is in state s
+func (p *Player) PlayTestTracks(s State, placement Placement, urls ...string) {
+	var cursor *insertCursor
+	if !placement.isAppend() {
+		cursor = &insertCursor{placement: placement}
+	}
+
+	for _, url := range urls {
+		t := newTestTrack(url)
+
+		p.withMemory(func(m *PlayerMemory) {
+			m.play(s, &playRequest{track: &t, cursor: cursor}, p.debug)
+		})
+	}
+}
+
+// BindTestTrack returns a context that is canceled once t is removed from the playlist
+func BindTestTrack(ctx context.Context, t Track) (context.Context, context.CancelFunc) {
+	return t.bindContext(ctx)
+}
+
+// NextTestTrack moves playback on to the next track and returns its url, or an empty string when playback stops
+func (p *Player) NextTestTrack() string {
+	t := p.nextTrack()
+	if t == nil {
+		return ""
+	}
+
+	return t.SrcUrlStr()
+}
+
+// PreviousTestTrack goes back to the track before the playing one
+func (p *Player) PreviousTestTrack() {
+	p.previousTrack(1)
+}
+
+// JumpTestTrack makes the track with the given url play next
+func (p *Player) JumpTestTrack(url string) bool {
+	return p.jumpToTrack(url)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+var ErrNotAudioAttachment = errors.New("attachment is not an audio or video file")
+
+var attachmentPathRegexp = regexp.MustCompile(`^/attachments/\d+/(?P<id>\d+)/(?P<filename>[^/]+)$`)
+
+// isAttachmentURL reports if u refers to a file attached to a discord message
+func isAttachmentURL(u *url.URL) bool {
+	if u.Host != "cdn.discordapp.com" && u.Host != "media.discordapp.net" {
+		return false
+	}
+
+	return attachmentPathRegexp.MatchString(u.Path)
+}
+
+// isMediaAttachment reports if an attachment could contain audio
+//
+// Attachments without a content type are given the benefit of the doubt.
+func isMediaAttachment(a *discordgo.MessageAttachment) bool {
+	return a.ContentType == "" || strings.HasPrefix(a.ContentType, "audio/") || strings.HasPrefix(a.ContentType, "video/")
+}
+
+// attachmentStream plays a file attached to a discord message
+//
+// The file is downloaded in full before it is transcoded so ffmpeg can detect
+// the container format, even for formats that are not streamable.
+type attachmentStream struct {
+	fileStream
+	downloadURL string
+}
+
+// newAttachmentStream expects urlStr to be an attachment url
+//
+// Discord signs attachment urls with expiring query parameters, so the url without
+// them identifies the track while the signed url is used to download it.
+func newAttachmentStream(p *service.Player, urlStr string) (*attachmentStream, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	match := attachmentPathRegexp.FindStringSubmatch(u.Path)
+	if match == nil {
+		return nil, fmt.Errorf("not an attachment url: %s", urlStr)
+	}
+
+	id := match[attachmentPathRegexp.SubexpIndex("id")]
+
+	title := match[attachmentPathRegexp.SubexpIndex("filename")]
+	if v, err := url.PathUnescape(title); err == nil {
+		title = v
+	}
+
+	downloadURL := u.String()
+
+	u.Host = "cdn.discordapp.com"
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	as := &attachmentStream{
+		fileStream:  newFileStream(p, u.String(), u.String(), path.Join(MediaCacheDir, "attachment-"+id, "audio.s16le"), service.TrackMetadata{Title: title}),
+		downloadURL: downloadURL,
+	}
+	as.transcode = as.downloadAndConvert
+
+	return as, nil
+}
+
+// downloadAndConvert downloads the attachment next to output and converts it
+func (as *attachmentStream) downloadAndConvert(ctx context.Context, output string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, as.downloadURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := audioStreamHttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download attachment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download attachment: %s", resp.Status)
+	}
+
+	srcFilePath, err := downloadToTemp(resp.Body, path.Dir(output), "melody-bot.*.attachment.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(srcFilePath)
+
+	cmd := transcodeCommand(ctx, "", srcFilePath, output)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("attachment conversion process failed: %w", err)
+	}
+
+	return nil
+}
+
+// playAttachments adds the audio and video files attached to a message to the playlist
+func playAttachments(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player) error {
+
+	var errs []error
+	for _, a := range m.Attachments {
+		if !isMediaAttachment(a) {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrNotAudioAttachment, a.Filename))
+			continue
+		}
+
+		if err := playWithPlacement(ctx, s, m, p, a.URL, service.Placement{}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to play %s: %w", a.Filename, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
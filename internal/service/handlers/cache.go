@@ -5,117 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"net/url"
-	"os"
 	"regexp"
-	"sync"
-	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/josephcopenhaver/melody-bot/internal/service"
 	"github.com/josephcopenhaver/melody-bot/internal/service/server/reactions"
 )
 
-type serialTaskRunner struct {
-	rwm       sync.RWMutex
-	startedAt time.Time
-	wg        sync.WaitGroup
-	taskChan  chan func(context.Context)
-}
-
-func newSerialTaskRunner(size int) *serialTaskRunner {
-	return &serialTaskRunner{
-		taskChan: make(chan func(context.Context), size),
-	}
-}
-
-func (tr *serialTaskRunner) Enqueue(f func(context.Context)) {
-	tr.taskChan <- f
-}
-
-func (tr *serialTaskRunner) Wait() {
-	tr.wg.Wait()
-}
-
-func (tr *serialTaskRunner) Start(ctx context.Context) {
-	tr.rwm.RLock()
-	cleanup := tr.rwm.RUnlock
-	defer func() {
-		if f := cleanup; f != nil {
-			cleanup = nil
-			f()
-		}
-	}()
-
-	if !tr.startedAt.IsZero() {
-		return
-	}
-
-	if f := cleanup; f != nil {
-		cleanup = nil
-		f()
-	}
-
-	tr.rwm.Lock()
-	cleanup = tr.rwm.Unlock
-
-	if !tr.startedAt.IsZero() {
-		return
-	}
-
-	wg := &tr.wg
-
-	taskChan := tr.taskChan
-
-	ctxDone := ctx.Done()
-	wg.Add(1)
-	tr.startedAt = time.Now()
-	go func() {
-		defer wg.Done()
-
-		for {
-			select {
-			case <-ctxDone:
-				return
-			default:
-			}
-			select {
-			case <-ctxDone:
-				return
-			case f := <-taskChan:
-				func() {
-					defer func() {
-						if r := recover(); r != nil {
-							err, ok := r.(error)
-							if !ok {
-								err = errors.New("cause unknown")
-							}
-							slog.ErrorContext(ctx,
-								"panic in serial downloader",
-								"error", err,
-							)
-						}
-					}()
-
-					f(ctx)
-				}()
-			}
-		}
-	}()
-}
-
-var serialDownloader *serialTaskRunner
-
-//nolint:gochecknoinits
-func init() {
-	serialDownloader = newSerialTaskRunner(128)
-}
-
-//nolint:revive
-func SerialDownloader() *serialTaskRunner {
-	return serialDownloader
-}
-
 func Cache() HandleMessageCreate {
 
 	return newHandleMessageCreate(
@@ -127,16 +23,23 @@ func Cache() HandleMessageCreate {
 			regexp.MustCompile(`^\s*cache\s+(?P<url>[^\s]+.*?)\s*$`),
 			func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
 
-				u, err := url.Parse(args["url"])
+				urlStr := args["url"]
+
+				src, err := findAudioSource(urlStr)
 				if err != nil {
 					return err
 				}
 
-				if u.Path == "/playlist" || u.Path == "/playlist/" {
-					return downloadPlaylistAudioStreamsAsync(ctx, p, u.String())
+				if src.isCollection() {
+					return downloadCollectionAudioStreamsAsync(ctx, p, src, urlStr)
 				}
 
-				return downloadAudioStreamInBackground(p, u.String())
+				as, err := src.stream(ctx, p, urlStr)
+				if err != nil {
+					return err
+				}
+
+				return enqueueDownload(ctx, p, as)
 			},
 		),
 	)
@@ -144,58 +47,50 @@ func Cache() HandleMessageCreate {
 
 var ErrPanicInCacher = errors.New("Panic in cacher")
 
-func downloadAudioStreamInBackground(p *service.Player, urlStr string) error {
-
-	as := &audioStream{
-		srcVideoUrlStr:   urlStr,
-		ytApiClient:      newYoutubeApiClient(),
-		ytDownloadClient: newYoutubeDownloadClient(),
+func enqueueDownload(ctx context.Context, p *service.Player, as service.AudioStreamer) error {
+	if v, ok := as.(service.LiveStreamer); ok && v.Live() {
+		return fmt.Errorf("%w: %s", service.ErrLiveStreamNotCacheable, as.SrcUrlStr())
 	}
 
-	SerialDownloader().
-		Enqueue(asyncDownloadFunc(p, as))
-
-	return nil
+	return p.TranscodeManager().Enqueue(ctx, service.TranscodeRequest{
+		GuildID:     p.GuildID(),
+		Key:         as.SrcUrlStr(),
+		Description: "cache " + as.SrcUrlStr(),
+		Priority:    service.TranscodePriorityBackground,
+		Run:         asyncDownloadFunc(p, as),
+	})
 }
 
-func downloadPlaylistAudioStreamsAsync(ctx context.Context, p *service.Player, urlStr string) error {
-
-	sd := SerialDownloader()
-
-	ac := newYoutubeApiClient()
+func downloadCollectionAudioStreamsAsync(ctx context.Context, p *service.Player, src *audioSource, urlStr string) error {
 
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	pl, err := ac.GetPlaylistContext(ctx, urlStr)
+	trackUrls, err := src.tracks(ctx, urlStr)
 	if err != nil {
-		return fmt.Errorf("failed to download playlist: %w", err)
+		return err
 	}
 
-	if len(pl.Videos) == 0 {
-		return errors.New("youtube playlist was empty")
+	if len(trackUrls) == 0 {
+		return errors.New(src.name + " was empty")
 	}
 
 	var numFailed, numSuccess int
-	for _, v := range pl.Videos {
+	for _, trackUrl := range trackUrls {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		as := &audioStream{
-			srcVideoUrlStr:   fmt.Sprintf("https://www.youtube.com/watch?v=%s", url.QueryEscape(v.ID)),
-			ytApiClient:      ac,
-			ytDownloadClient: newYoutubeDownloadClient(),
-		}
-
-		if err := as.SelectDownloadURLWithFallbackApiClient(ctx, newYoutubeApiClient); err != nil {
+		as, err := resolveTrack(ctx, p, trackUrl)
+		if err != nil {
 			slog.ErrorContext(ctx,
-				"failed to select download url",
-				"track", as.srcVideoUrlStr,
+				"failed to resolve track",
+				"error", err,
+				"track", trackUrl,
 			)
 
-			p.BroadcastTextMessage("Failed to queue " + as.srcVideoUrlStr)
+			p.BroadcastTextMessage("Failed to queue " + trackUrl)
 
 			numFailed++
 			continue
@@ -207,7 +102,9 @@ func downloadPlaylistAudioStreamsAsync(ctx context.Context, p *service.Player, u
 			return err
 		}
 
-		sd.Enqueue(asyncDownloadFunc(p, as))
+		if err := enqueueDownload(ctx, p, as); err != nil {
+			return err
+		}
 	}
 
 	if numFailed > 0 {
@@ -220,7 +117,7 @@ func downloadPlaylistAudioStreamsAsync(ctx context.Context, p *service.Player, u
 	return nil
 }
 
-func asyncDownloadFunc(p *service.Player, as *audioStream) func(context.Context) {
+func asyncDownloadFunc(p *service.Player, as service.AudioStreamer) func(context.Context) {
 	return func(ctx context.Context) {
 		var err error
 		defer func() {
@@ -241,27 +138,18 @@ func asyncDownloadFunc(p *service.Player, as *audioStream) func(context.Context)
 			p.BroadcastTextMessage(err.Error())
 		}()
 
-		// establish the dstFilePath via the download url
-		if e := as.SelectDownloadURL(ctx); e != nil {
-			err = e
+		if as.Cached() {
+			p.BroadcastTextMessage(fmt.Sprintf("audio file for %s is already cached", as.SrcUrlStr()))
 			return
 		}
 
-		if inf, e := os.Stat(as.dstFilePath); e != nil {
-			if !os.IsNotExist(e) {
-				err = e
+		if e := as.DownloadAndTranscode(ctx); e != nil {
+			if errors.Is(context.Cause(ctx), service.ErrTranscodeJobPreempted) {
+				err = fmt.Errorf("cache: caching %s was interrupted to play a track, cache it again later", as.SrcUrlStr())
 				return
 			}
-		} else if inf.IsDir() {
-			err = errors.New("cannot download: destination file exists as a directory")
-			return
-		} else {
-			p.BroadcastTextMessage(fmt.Sprintf("audio file for %s is already cached", as.srcVideoUrlStr))
-			return
-		}
 
-		if e := as.DownloadAndTranscode(ctx); e != nil {
-			err = fmt.Errorf("cache: download and transcode process for %s failed: %w", as.srcVideoUrlStr, e)
+			err = fmt.Errorf("cache: download and transcode process for %s failed: %w", as.SrcUrlStr(), e)
 		}
 	}
 }
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Controls() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"controls",
+		"controls",
+		"shows a now playing message with playback buttons that stays up to date; only the most recent one is kept",
+		newWordMatcher(
+			true,
+			[]string{"controls"},
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player) error {
+				return p.ShowControls(m.ChannelID)
+			},
+		),
+	)
+}
+
+// ControlsButton performs the action of a button clicked on a controls message
+func ControlsButton() HandleMessageComponent {
+
+	return newHandleMessageComponent(
+		"controls-button",
+		service.ControlsCustomIDPrefix,
+		func(_ context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, p *service.Player, action string) error {
+
+			var act func()
+
+			switch action {
+			case service.ControlsActionPrevious:
+				act = func() { p.Previous(i) }
+			case service.ControlsActionPause:
+				act = func() { p.Pause(i) }
+			case service.ControlsActionResume:
+				act = func() { p.Resume(i) }
+			case service.ControlsActionNext:
+				act = func() { p.Next(i) }
+			case service.ControlsActionStop:
+				act = func() { p.Stop(i) }
+			case service.ControlsActionRepeat:
+				act = func() { p.CycleRepeatMode() }
+			case service.ControlsActionShuffle:
+				act = func() { p.SetShuffleMode(!p.Shuffling()) }
+			default:
+				return fmt.Errorf("unknown controls action: %q", action)
+			}
+
+			// respond before acting: a signal can block while the player is busy,
+			// which would let the interaction expire
+			//
+			// the player edits the message once its state changes
+			if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredMessageUpdate,
+			}); err != nil {
+				return err
+			}
+
+			act()
+
+			return nil
+		},
+	)
+}
@@ -0,0 +1,5 @@
+package handlers
+
+var DuplicateTracks = duplicateTracks
+
+var SplitStartOffset = splitStartOffset
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/josephcopenhaver/melody-bot/internal/logging"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+// fileStream is a stream that is transcoded in full to a file in the media cache before it is played
+//
+// Sources embed it and provide the transcode func.
+type fileStream struct {
+	playContext
+	guildID   string
+	tm        *service.TranscodeManager
+	srcUrlStr string
+	// cacheKey identifies the stream's entry in the metadata cache
+	cacheKey     string
+	dstFilePath  string
+	transcodeSem chan struct{}
+	meta         service.TrackMetadata
+	// transcode converts the source to the player's format and writes it to the output file
+	transcode func(ctx context.Context, output string) error
+}
+
+func newFileStream(p *service.Player, srcUrlStr, cacheKey, dstFilePath string, meta service.TrackMetadata) fileStream {
+	return fileStream{
+		guildID:      p.GuildID(),
+		tm:           p.TranscodeManager(),
+		srcUrlStr:    srcUrlStr,
+		cacheKey:     cacheKey,
+		dstFilePath:  dstFilePath,
+		transcodeSem: make(chan struct{}, 1),
+		meta:         meta,
+	}
+}
+
+// lockTranscode ensures only one download and transcode of the stream happens at a time
+//
+// The returned func releases the lock.
+func (fs *fileStream) lockTranscode(ctx context.Context) (func(), error) {
+	ctxDone := ctx.Done()
+	select {
+	case <-ctxDone:
+		return nil, ctx.Err()
+	case fs.transcodeSem <- struct{}{}:
+	}
+
+	return func() {
+		<-fs.transcodeSem
+	}, nil
+}
+
+func (fs *fileStream) SrcUrlStr() string {
+	return fs.srcUrlStr
+}
+
+func (fs *fileStream) Metadata() service.TrackMetadata {
+	return fs.meta
+}
+
+func (fs *fileStream) Cached() bool {
+	info, err := os.Stat(fs.dstFilePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error(
+				"failed to stat file system",
+				"error", err,
+			)
+		}
+
+		return false
+	}
+
+	return info.Size() > 0
+}
+
+// Duration is measured from the transcoded file, before that it is only known when the source reports it
+func (fs *fileStream) Duration() (time.Duration, bool) {
+	if info, err := os.Stat(fs.dstFilePath); err == nil && info.Size() > 0 {
+		return service.BytesToDuration(info.Size()), true
+	}
+
+	if fs.meta.Duration > 0 {
+		return fs.meta.Duration, true
+	}
+
+	return 0, false
+}
+
+func (fs *fileStream) Loudness() (service.Loudness, bool) {
+	var result service.Loudness
+
+	v, ok, err := vidMetadataCache.Get(fs.cacheKey)
+	if err != nil || !ok || v.Loudness == nil {
+		return result, false
+	}
+
+	result = *v.Loudness
+	return result, true
+}
+
+// analyzeLoudness measures the loudness of a file and records it in the metadata cache
+func (fs *fileStream) analyzeLoudness(ctx context.Context, filePath string) {
+	l, err := measureLoudness(ctx, filePath)
+	if err != nil {
+		if ctx.Err() == nil {
+			logging.Context(ctx).ErrorContext(ctx,
+				"failed to measure loudness",
+				"error", err,
+				"src_url", fs.srcUrlStr,
+				"file", filePath,
+			)
+		}
+		return
+	}
+
+	err = vidMetadataCache.Set(fs.cacheKey, MediaMetaCacheEntry{
+		Loudness: &l,
+		Metadata: fs.meta,
+	})
+	if err != nil {
+		logging.Context(ctx).ErrorContext(ctx,
+			"failed to save loudness to a metadata cache entry",
+			"error", err,
+			"key", fs.cacheKey,
+		)
+	}
+}
+
+// scheduleLoudnessAnalysis measures the loudness of the cached file in the background
+func (fs *fileStream) scheduleLoudnessAnalysis(ctx context.Context, wg *sync.WaitGroup) {
+	enqueueLoudnessAnalysis(ctx, wg, fs.tm, fs.guildID, fs.srcUrlStr,
+		func() bool {
+			_, ok := fs.Loudness()
+			return ok
+		},
+		func(ctx context.Context) {
+			fs.analyzeLoudness(ctx, fs.dstFilePath)
+		},
+	)
+}
+
+// ReadCloser plays the stream while it is transcoded to the media cache
+//
+// Only the transcode runs on the transcode manager, the returned file is read
+// outside of it so playback never holds a worker.
+func (fs *fileStream) ReadCloser(ctx context.Context, wg *sync.WaitGroup) (io.ReadCloser, error) {
+
+	if fs.Cached() {
+		logging.Context(ctx).DebugContext(ctx,
+			"playing from cache",
+			"url", fs.srcUrlStr,
+			"cached_file", fs.dstFilePath,
+		)
+		return os.Open(fs.dstFilePath)
+	}
+
+	tf, ctx := service.NewTranscodingFile(ctx)
+
+	wg.Add(1)
+	err := fs.tm.Enqueue(ctx, service.TranscodeRequest{
+		GuildID:     fs.guildID,
+		Key:         fs.srcUrlStr,
+		Description: "play " + fs.srcUrlStr,
+		Priority:    service.TranscodePriorityNowPlaying,
+		Run: func(ctx context.Context) {
+			defer wg.Done()
+
+			err := fs.transcodeToCache(ctx, tf.Start)
+			tf.Finish(err)
+
+			if err == nil {
+				fs.scheduleLoudnessAnalysis(ctx, wg)
+			}
+		},
+	})
+	if err != nil {
+		wg.Done()
+		tf.Close()
+		return nil, fmt.Errorf("failed to schedule transcode: %w", err)
+	}
+
+	return tf, nil
+}
+
+// DownloadAndTranscode synchronously transcodes the stream to the media cache and measures its loudness
+func (fs *fileStream) DownloadAndTranscode(ctx context.Context) error {
+	if err := fs.transcodeToCache(ctx, nil); err != nil {
+		return err
+	}
+
+	if _, ok := fs.Loudness(); !ok {
+		fs.analyzeLoudness(ctx, fs.dstFilePath)
+	}
+
+	return nil
+}
+
+// transcodeToCache transcodes the stream to the media cache unless it is already cached
+//
+// When started is not nil it is given the file being written before the
+// transcode begins, or the cached file when there is nothing to do.
+func (fs *fileStream) transcodeToCache(ctx context.Context, started func(filePath string) error) error {
+
+	unlock, err := fs.lockTranscode(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if fs.Cached() {
+		if started != nil {
+			return started(fs.dstFilePath)
+		}
+
+		return nil
+	}
+
+	slog.Debug(
+		"downloading and transcoding to cache",
+		"url", fs.srcUrlStr,
+	)
+
+	cacheDir := path.Dir(fs.dstFilePath)
+
+	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to make cache directory: %s: %w", cacheDir, err)
+	}
+
+	tmpF, err := os.CreateTemp(cacheDir, "melody-bot.*.audio.s16le.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpFilePath := tmpF.Name()
+
+	ignoredErr := tmpF.Close()
+	_ = ignoredErr
+
+	cleanup := func() {
+		os.Remove(tmpFilePath)
+	}
+	defer func() {
+		if f := cleanup; f != nil {
+			cleanup = nil
+			f()
+		}
+	}()
+
+	if started != nil {
+		if err := started(tmpFilePath); err != nil {
+			return err
+		}
+	}
+
+	if err := fs.transcode(ctx, tmpFilePath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpFilePath, fs.dstFilePath); err != nil {
+		slog.Error(
+			"failed to rename file",
+			"src", tmpFilePath,
+			"dst", fs.dstFilePath,
+		)
+
+		return err
+	}
+
+	cleanup = nil
+
+	return nil
+}
+
+// downloadToTemp saves everything read from r to a new temporary file in dir and returns its path
+func downloadToTemp(r io.Reader, dir, pattern string) (string, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to download: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Forward() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"forward",
+		"forward <secs>",
+		"skips ahead in the current track by a number of seconds",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*forward\s+(?P<secs>\d+)\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				secs, err := strconv.Atoi(args["secs"])
+				if err != nil {
+					return err
+				}
+
+				p.SeekRelative(m, time.Duration(secs)*time.Second)
+
+				return nil
+			},
+		),
+	)
+}
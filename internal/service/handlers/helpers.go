@@ -9,6 +9,9 @@ import (
 	"github.com/josephcopenhaver/melody-bot/internal/service"
 )
 
+// maxMessageLen is the most characters discord accepts in a message
+const maxMessageLen = 2000
+
 type HandleMessageCreate struct {
 	Name        string
 	Usage       string
@@ -16,6 +19,24 @@ type HandleMessageCreate struct {
 	Matcher     func(*service.Player, string) func(context.Context, *discordgo.Session, *discordgo.MessageCreate, *service.Player, *service.Brain) error
 }
 
+// HandleMessageComponent handles interactions with message components, such as buttons, sent by the bot
+//
+// Interactions whose custom id starts with CustomIDPrefix are routed to Handler along
+// with the remainder of the custom id.
+type HandleMessageComponent struct {
+	Name           string
+	CustomIDPrefix string
+	Handler        func(context.Context, *discordgo.Session, *discordgo.InteractionCreate, *service.Player, string) error
+}
+
+func newHandleMessageComponent(name, customIDPrefix string, handler func(context.Context, *discordgo.Session, *discordgo.InteractionCreate, *service.Player, string) error) HandleMessageComponent {
+	return HandleMessageComponent{
+		Name:           name,
+		CustomIDPrefix: customIDPrefix,
+		Handler:        handler,
+	}
+}
+
 func newHandleMessageCreate(name, usage, description string, matcher func(*service.Player, string) func(context.Context, *discordgo.Session, *discordgo.MessageCreate, *service.Player) error) HandleMessageCreate {
 	return HandleMessageCreate{
 		Name:        name,
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+const (
+	// httpSniffSize is how many bytes are read from the start of a file to detect its format
+	httpSniffSize = 4096
+	// httpMaxResumes is how many times a dropped download is resumed with a range request
+	httpMaxResumes = 5
+)
+
+var ErrUnknownAudioFormat = errors.New("url does not refer to a recognized audio format")
+
+// httpProbe is what is learned about an http resource before it is played
+type httpProbe struct {
+	format        service.AudioFormat
+	contentType   string
+	filename      string
+	size          int64 // -1 when unknown
+	validator     string
+	acceptsRanges bool
+	// live streams, such as internet radio, never end
+	live bool
+	// stationName is the name a live stream gives itself
+	stationName string
+}
+
+// probeHTTPAudio fetches the first bytes of a url to detect its format and how it can be downloaded
+func probeHTTPAudio(ctx context.Context, urlStr string) (httpProbe, error) {
+	result := httpProbe{
+		size: -1,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Range", "bytes=0-"+strconv.Itoa(httpSniffSize-1))
+
+	resp, err := apiHttpClient.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		result.acceptsRanges = true
+
+		// Content-Range: bytes 0-4095/12345
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if n, err := strconv.ParseInt(total, 10, 64); err == nil {
+				result.size = n
+			}
+		}
+	case http.StatusOK:
+		// the range was ignored, the body is the whole file
+		result.acceptsRanges = resp.Header.Get("Accept-Ranges") == "bytes"
+		result.size = resp.ContentLength
+	default:
+		return result, fmt.Errorf("failed to fetch url: %s", resp.Status)
+	}
+
+	head := make([]byte, httpSniffSize)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return result, err
+	}
+	head = head[:n]
+
+	result.contentType = resp.Header.Get("Content-Type")
+	result.live = isLiveResponse(resp)
+	result.stationName = strings.TrimSpace(resp.Header.Get("Icy-Name"))
+
+	format, ok := service.DetectAudioFormat(result.contentType, head)
+	if !ok {
+		return result, fmt.Errorf("%w: %s", ErrUnknownAudioFormat, result.contentType)
+	}
+	result.format = format
+
+	if result.live {
+		if !format.Streamable {
+			return result, fmt.Errorf("live stream format cannot be played as it is received: %s", format.Name)
+		}
+
+		return result, nil
+	}
+
+	// ranges can only resume a download when the resource is known not to have changed
+	if v := resp.Header.Get("ETag"); v != "" && !strings.HasPrefix(v, "W/") {
+		result.validator = v
+	} else {
+		result.validator = resp.Header.Get("Last-Modified")
+	}
+	if result.validator == "" {
+		result.acceptsRanges = false
+	}
+
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		result.filename = params["filename"]
+	}
+
+	return result, nil
+}
+
+// isLiveResponse reports if a response is an endless stream, such as an icecast or shoutcast radio station
+func isLiveResponse(resp *http.Response) bool {
+	for k := range resp.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "icy-") || k == "ice-audio-info" {
+			return true
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.ContentLength >= 0 {
+		return false
+	}
+
+	server := strings.ToLower(resp.Header.Get("Server"))
+	return strings.Contains(server, "icecast") || strings.Contains(server, "shoutcast")
+}
+
+// httpTitle names an http resource after what the server calls it, falling back to its url
+func httpTitle(urlStr string, probe httpProbe) string {
+	title := probe.stationName
+	if title == "" {
+		title = probe.filename
+	}
+	if title == "" {
+		if u, err := url.Parse(urlStr); err == nil {
+			title = path.Base(u.Path)
+		}
+	}
+	if title == "" || title == "/" || title == "." {
+		title = urlStr
+	}
+
+	return title
+}
+
+// rangeReader reads an http resource, resuming with range requests when the connection drops
+type rangeReader struct {
+	ctx       context.Context
+	urlStr    string
+	probe     httpProbe
+	body      io.ReadCloser
+	offset    int64
+	numResume int
+}
+
+func newRangeReader(ctx context.Context, urlStr string, probe httpProbe) *rangeReader {
+	return &rangeReader{
+		ctx:    ctx,
+		urlStr: urlStr,
+		probe:  probe,
+	}
+}
+
+func (r *rangeReader) open() error {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.urlStr, nil)
+	if err != nil {
+		return err
+	}
+
+	if r.offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(r.offset, 10)+"-")
+		req.Header.Set("If-Range", r.probe.validator)
+	}
+
+	resp, err := audioStreamHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	expected := http.StatusOK
+	if r.offset > 0 {
+		expected = http.StatusPartialContent
+	}
+
+	if resp.StatusCode != expected {
+		resp.Body.Close()
+
+		if r.offset > 0 && resp.StatusCode == http.StatusOK {
+			return errors.New("file changed on the server while downloading")
+		}
+
+		return fmt.Errorf("failed to download url: %s", resp.Status)
+	}
+
+	r.body = resp.Body
+
+	return nil
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for {
+		if r.body == nil {
+			if err := r.open(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+
+		if errors.Is(err, io.EOF) && r.probe.size >= 0 && r.offset < r.probe.size {
+			err = io.ErrUnexpectedEOF
+		}
+
+		if err == nil || errors.Is(err, io.EOF) {
+			return n, err
+		}
+
+		// the connection dropped
+		r.body.Close()
+		r.body = nil
+
+		if !r.probe.acceptsRanges || r.numResume >= httpMaxResumes || r.ctx.Err() != nil {
+			return n, err
+		}
+
+		r.numResume++
+
+		slog.Debug(
+			"resuming download",
+			"error", err,
+			"url", r.urlStr,
+			"offset", r.offset,
+		)
+
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *rangeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+
+	return err
+}
+
+// httpStream plays an audio file served over http(s)
+//
+// The transcoded file is cached under the url and the server's validator for
+// the file, so a file that changes on the server is downloaded again.
+type httpStream struct {
+	fileStream
+	probe httpProbe
+}
+
+func newHTTPStream(p *service.Player, urlStr string, probe httpProbe) *httpStream {
+	cacheKey := urlStr
+	if probe.validator != "" {
+		cacheKey += "#" + probe.validator
+	}
+
+	sum := sha256.Sum256([]byte(cacheKey))
+
+	hs := &httpStream{
+		fileStream: newFileStream(p, urlStr, cacheKey, path.Join(MediaCacheDir, "http-"+hex.EncodeToString(sum[:16]), "audio.s16le"), service.TrackMetadata{Title: httpTitle(urlStr, probe)}),
+		probe:      probe,
+	}
+	hs.transcode = hs.convert
+
+	return hs
+}
+
+// convert downloads the file and converts it to the player's format, writing it to output
+func (hs *httpStream) convert(ctx context.Context, output string) error {
+	body := newRangeReader(ctx, hs.srcUrlStr, hs.probe)
+	defer body.Close()
+
+	input := "pipe:"
+	if !hs.probe.format.Streamable {
+		srcFilePath, err := downloadToTemp(body, path.Dir(hs.dstFilePath), "melody-bot.*.http.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(srcFilePath)
+
+		input = srcFilePath
+	}
+
+	cmd := transcodeCommand(ctx, hs.probe.format.Name, input, output)
+	if input == "pipe:" {
+		cmd.Stdin = body
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("stream conversion process failed: %w", err)
+	}
+
+	return nil
+}
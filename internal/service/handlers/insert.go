@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Insert() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"insert",
+		"insert <position> <url|search terms>",
+		"adds a track, youtube playlist or search match at a playlist position, 1 being the first track",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*insert\s+(?P<position>\d+)\s+(?P<url>[^\s]+.*?)\s*$`),
+			func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				pos, err := strconv.Atoi(args["position"])
+				if err != nil {
+					return err
+				}
+
+				if pos < 1 {
+					return errors.New("position must be 1 or greater")
+				}
+
+				return playWithPlacement(ctx, s, m, p, args["url"], service.Placement{Position: pos})
+			},
+		),
+	)
+}
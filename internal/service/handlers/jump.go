@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Jump() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"jump",
+		"<jump|skip to> <position|text>",
+		"starts playback at a playlist position, 1 being the first track, or at the first track whose title contains the text",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*(?:jump(?:\s+to)?|skip\s+to)\s+(?P<target>[^\s]+.*?)\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				playlist := p.GetPlaylist()
+
+				i, err := findTrack(playlist, args["target"])
+				if err != nil {
+					return err
+				}
+
+				p.JumpToTrack(m, playlist.Tracks[i].SrcUrlStr())
+
+				return nil
+			},
+		),
+	)
+}
+
+// findTrack resolves a 1-based playlist position or case-insensitive title text to a playlist index
+func findTrack(playlist service.Playlist, target string) (int, error) {
+
+	if n, err := strconv.Atoi(target); err == nil {
+		if n < 1 || n > len(playlist.Tracks) {
+			return -1, fmt.Errorf("no track at position %d: the playlist has %d tracks", n, len(playlist.Tracks))
+		}
+
+		return n - 1, nil
+	}
+
+	text := strings.ToLower(target)
+	for i, t := range playlist.Tracks {
+		if strings.Contains(strings.ToLower(t.Title()), text) {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("no track found matching %q", target)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func LibraryRescan() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"library-rescan",
+		"library rescan",
+		"indexes files added to, changed in, or removed from the local library directory",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*library\s+rescan\s*$`),
+			func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, _ map[string]string) error {
+
+				lib := p.Library()
+				if !lib.Enabled() {
+					return service.ErrLibraryDisabled
+				}
+
+				if _, err := s.ChannelMessageSend(m.ChannelID, "rescanning library"); err != nil {
+					return err
+				}
+
+				result, err := lib.Rescan(ctx)
+				if err != nil {
+					return err
+				}
+
+				msg := fmt.Sprintf(
+					"library rescanned: %d tracks\nadded: %d\nupdated: %d\nremoved: %d\nunreadable: %d",
+					lib.Len(),
+					result.Added,
+					result.Updated,
+					result.Removed,
+					result.Failed,
+				)
+
+				_, err = s.ChannelMessageSend(m.ChannelID, msg)
+				return err
+			},
+		),
+	)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func LibrarySearch() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"library-search",
+		"library search <text>",
+		"lists local library tracks matching the text; play one with its button or with pick",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*library\s+search\s+(?P<text>.+?)\s*$`),
+			func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				results, err := p.Library().Search(ctx, args["text"], service.SearchResultLimit)
+				if err != nil {
+					return err
+				}
+
+				if len(results) == 0 {
+					return service.ErrNoSearchResults
+				}
+
+				return sendSearchResults(s, m, p, results)
+			},
+		),
+	)
+}
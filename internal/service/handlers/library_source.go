@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+var libraryIDRegexp = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// isLibraryURL reports if u refers to a track of the local library, as in library:<id>
+func isLibraryURL(u *url.URL) bool {
+	return u.Scheme == service.LibraryURLScheme && libraryIDRegexp.MatchString(u.Opaque)
+}
+
+// libraryStream plays an audio file of the local library
+//
+// The transcoded file is cached under the track's id and version, so a file
+// that changes on disk is transcoded again after a rescan.
+type libraryStream struct {
+	fileStream
+	filePath string
+}
+
+func newLibraryStream(p *service.Player, urlStr string) (*libraryStream, error) {
+	lib := p.Library()
+	if lib == nil || !lib.Enabled() {
+		return nil, service.ErrLibraryDisabled
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := lib.Track(u.Opaque)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := t.URL() + "#" + t.Version()
+
+	sum := sha256.Sum256([]byte(cacheKey))
+
+	meta := service.TrackMetadata{
+		Title:    t.Name(),
+		Channel:  t.Artist,
+		Duration: t.Duration,
+	}
+
+	ls := &libraryStream{
+		fileStream: newFileStream(p, t.URL(), cacheKey, path.Join(MediaCacheDir, "library-"+hex.EncodeToString(sum[:16]), "audio.s16le"), meta),
+		filePath:   lib.FilePath(t),
+	}
+	ls.transcode = ls.convert
+
+	return ls, nil
+}
+
+func (ls *libraryStream) convert(ctx context.Context, output string) error {
+	cmd := transcodeCommand(ctx, "", ls.filePath, output)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("library file conversion process failed: %w", err)
+	}
+
+	return nil
+}
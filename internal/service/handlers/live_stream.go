@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/josephcopenhaver/melody-bot/internal/logging"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+const (
+	// liveMaxReconnects is how many times in a row a dropped live stream is reconnected before giving up
+	liveMaxReconnects = 5
+	// liveStableAfter is how long a connection must last for its drop to no longer count towards liveMaxReconnects
+	liveStableAfter = 30 * time.Second
+	// liveReconnectDelay is the wait before the first reconnect, it doubles with each consecutive drop
+	liveReconnectDelay = time.Second
+	// liveIdleTimeout is how long a connection can go without sending data before it is considered dropped
+	liveIdleTimeout = 15 * time.Second
+)
+
+var ErrLiveStreamEnded = errors.New("live stream ended")
+
+// liveStreamHttpClient has no timeout because live streams never finish, stalls are detected by liveIdleTimeout instead
+var liveStreamHttpClient = http.Client{}
+
+// idleTimeoutReader closes its source when no data is read for a while, making a stalled Read fail
+type idleTimeoutReader struct {
+	rc    io.ReadCloser
+	timer *time.Timer
+	d     time.Duration
+}
+
+func newIdleTimeoutReader(rc io.ReadCloser, d time.Duration) *idleTimeoutReader {
+	return &idleTimeoutReader{
+		rc: rc,
+		timer: time.AfterFunc(d, func() {
+			ignoredErr := rc.Close()
+			_ = ignoredErr
+		}),
+		d: d,
+	}
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.d)
+	}
+
+	return n, err
+}
+
+func (r *idleTimeoutReader) Close() error {
+	r.timer.Stop()
+
+	return r.rc.Close()
+}
+
+// liveStream plays an endless http audio stream, such as an icecast radio station
+//
+// The stream is transcoded as it is received and never cached. When the
+// connection drops it is reconnected, so playback only ends when the track is
+// skipped or stopped, or when the stream cannot be reconnected.
+type liveStream struct {
+	playContext
+	srcUrlStr string
+	format    service.AudioFormat
+	meta      service.TrackMetadata
+}
+
+func newLiveStream(urlStr string, probe httpProbe) *liveStream {
+	return &liveStream{
+		srcUrlStr: urlStr,
+		format:    probe.format,
+		meta:      service.TrackMetadata{Title: httpTitle(urlStr, probe)},
+	}
+}
+
+func (ls *liveStream) Live() bool {
+	return true
+}
+
+func (ls *liveStream) SrcUrlStr() string {
+	return ls.srcUrlStr
+}
+
+func (ls *liveStream) Metadata() service.TrackMetadata {
+	return ls.meta
+}
+
+func (ls *liveStream) Cached() bool {
+	return false
+}
+
+func (ls *liveStream) Duration() (time.Duration, bool) {
+	return 0, false
+}
+
+// Loudness is never known because a live stream cannot be analyzed ahead of time
+func (ls *liveStream) Loudness() (service.Loudness, bool) {
+	return service.Loudness{}, false
+}
+
+func (ls *liveStream) DownloadAndTranscode(context.Context) error {
+	return service.ErrLiveStreamNotCacheable
+}
+
+// ReadCloser transcodes the stream as it is received
+//
+// It does not run on the transcode manager because it would hold a worker for
+// as long as the stream plays.
+func (ls *liveStream) ReadCloser(ctx context.Context, wg *sync.WaitGroup) (io.ReadCloser, error) {
+
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(ctx)
+	result := readCloser{
+		read: pr.Read,
+		close: func() error {
+			defer cancel()
+
+			return pr.Close()
+		},
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		pw.CloseWithError(ls.stream(ctx, pw))
+	}()
+
+	return &result, nil
+}
+
+// stream writes the transcoded stream to w, reconnecting whenever the connection drops
+//
+// It only returns once ctx is done or the stream could not be reconnected.
+func (ls *liveStream) stream(ctx context.Context, w io.Writer) error {
+	var numDrops int
+	for {
+		connectedAt := time.Now()
+
+		err := ls.transcodeConnection(ctx, w)
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+
+		if err == nil {
+			err = ErrLiveStreamEnded
+		}
+
+		if time.Since(connectedAt) >= liveStableAfter {
+			numDrops = 0
+		}
+		numDrops++
+
+		if numDrops > liveMaxReconnects {
+			return fmt.Errorf("live stream could not be reconnected after %d attempts: %w", liveMaxReconnects, err)
+		}
+
+		delay := liveReconnectDelay << (numDrops - 1)
+
+		logging.Context(ctx).WarnContext(ctx,
+			"live stream dropped, reconnecting",
+			"error", err,
+			"url", ls.srcUrlStr,
+			"attempt", numDrops,
+			"delay", delay,
+		)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// transcodeConnection connects to the stream and transcodes it to w until the connection drops
+func (ls *liveStream) transcodeConnection(ctx context.Context, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ls.srcUrlStr, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := liveStreamHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	body := newIdleTimeoutReader(resp.Body, liveIdleTimeout)
+	defer body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to connect to live stream: %s", resp.Status)
+	}
+
+	slog.Debug(
+		"connected to live stream",
+		"url", ls.srcUrlStr,
+	)
+
+	cmd := transcodeCommand(ctx, ls.format.Name, "pipe:", "pipe:1")
+	cmd.Stdin = body
+	cmd.Stdout = w
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("live stream conversion process failed: %w", err)
+	}
+
+	return nil
+}
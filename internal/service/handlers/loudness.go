@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+
+	"github.com/josephcopenhaver/melody-bot/internal/logging"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+var ErrNoLoudnessReport = errors.New("no loudness report found in ffmpeg output")
+
+// measureLoudness runs an EBU R128 analysis pass over a transcoded s16le file
+func measureLoudness(ctx context.Context, filePath string) (service.Loudness, error) {
+	var result service.Loudness
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-nostats", "-f", "s16le", "-ar", strconv.Itoa(service.SampleRate), "-ac", "1", "-i", filePath, "-af", "loudnorm=print_format=json", "-f", "null", "-")
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return result, fmt.Errorf("loudness analysis process failed: %w", err)
+	}
+
+	// the report is the last json object ffmpeg writes
+	out := stderr.Bytes()
+	start := bytes.LastIndexByte(out, '{')
+	end := bytes.LastIndexByte(out, '}')
+	if start == -1 || end < start {
+		return result, ErrNoLoudnessReport
+	}
+
+	var report struct {
+		InputI  string `json:"input_i"`
+		InputTP string `json:"input_tp"`
+	}
+	if err := json.Unmarshal(out[start:end+1], &report); err != nil {
+		return result, fmt.Errorf("failed to parse loudness report: %w", err)
+	}
+
+	i, err := strconv.ParseFloat(report.InputI, 64)
+	if err != nil {
+		return result, fmt.Errorf("failed to parse integrated loudness: %w", err)
+	}
+
+	tp, err := strconv.ParseFloat(report.InputTP, 64)
+	if err != nil {
+		return result, fmt.Errorf("failed to parse true peak: %w", err)
+	}
+
+	result = service.Loudness{
+		IntegratedLUFS: i,
+		TruePeakDBTP:   tp,
+	}
+	return result, nil
+}
+
+func (as *audioStream) Loudness() (service.Loudness, bool) {
+	var result service.Loudness
+
+	v, ok, err := vidMetadataCache.Get(as.srcVideoUrlStr)
+	if err != nil || !ok || v.Loudness == nil {
+		return result, false
+	}
+
+	result = *v.Loudness
+	return result, true
+}
+
+// saveLoudness records the loudness of the stream in its metadata cache entry
+func (as *audioStream) saveLoudness(ctx context.Context, l service.Loudness) {
+	v, ok, err := vidMetadataCache.Get(as.srcVideoUrlStr)
+	if err != nil || !ok {
+		logging.Context(ctx).ErrorContext(ctx,
+			"failed to find video metadata cache entry for loudness",
+			"error", err,
+			"key", as.srcVideoUrlStr,
+		)
+		return
+	}
+
+	v.Loudness = &l
+
+	if err := vidMetadataCache.Set(as.srcVideoUrlStr, v); err != nil {
+		logging.Context(ctx).ErrorContext(ctx,
+			"failed to save loudness to a video metadata cache entry",
+			"error", err,
+			"key", as.srcVideoUrlStr,
+		)
+	}
+}
+
+// analyzeLoudness measures the loudness of a file and records it in the stream's metadata
+func (as *audioStream) analyzeLoudness(ctx context.Context, filePath string) {
+	l, err := measureLoudness(ctx, filePath)
+	if err != nil {
+		if ctx.Err() == nil {
+			logging.Context(ctx).ErrorContext(ctx,
+				"failed to measure loudness",
+				"error", err,
+				"src_url", as.srcVideoUrlStr,
+				"file", filePath,
+			)
+		}
+		return
+	}
+
+	as.saveLoudness(ctx, l)
+}
+
+// scheduleLoudnessAnalysis measures the loudness of the cached file in the background
+//
+// It is used when a stream is cached as a side effect of playing it.
+func (as *audioStream) scheduleLoudnessAnalysis(ctx context.Context, wg *sync.WaitGroup) {
+	enqueueLoudnessAnalysis(ctx, wg, as.tm, as.guildID, as.srcVideoUrlStr,
+		func() bool {
+			_, ok := as.Loudness()
+			return ok
+		},
+		func(ctx context.Context) {
+			as.analyzeLoudness(ctx, as.dstFilePath)
+		},
+	)
+}
+
+// enqueueLoudnessAnalysis runs analyze as a background job unless analyzed reports the loudness is already known
+//
+// The analysis must outlive playback of the track, so it does not inherit the
+// cancellation of ctx. A track is only measured once its transcode has finished,
+// so the first play of a track that was not cached beforehand is not normalized.
+func enqueueLoudnessAnalysis(ctx context.Context, wg *sync.WaitGroup, tm *service.TranscodeManager, guildID, srcUrlStr string, analyzed func() bool, analyze func(context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		err := tm.Enqueue(ctx, service.TranscodeRequest{
+			GuildID:     guildID,
+			Key:         srcUrlStr,
+			Description: "analyze loudness " + srcUrlStr,
+			Priority:    service.TranscodePriorityBackground,
+			Run: func(ctx context.Context) {
+				if ctx.Err() != nil {
+					return
+				}
+
+				if analyzed() {
+					return
+				}
+
+				analyze(ctx)
+			},
+		})
+		if err != nil && !errors.Is(err, service.ErrTranscodeManagerNotRunning) {
+			logging.Context(ctx).ErrorContext(ctx,
+				"failed to schedule loudness analysis",
+				"error", err,
+				"src_url", srcUrlStr,
+			)
+		}
+	}()
+}
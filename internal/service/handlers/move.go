@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Move() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"move",
+		"move <from> <to>",
+		"moves the track at one playlist position to another, 1 being the first track",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*move\s+(?P<from>\d+)\s+(?P<to>\d+)\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				from, err := strconv.Atoi(args["from"])
+				if err != nil {
+					return err
+				}
+
+				to, err := strconv.Atoi(args["to"])
+				if err != nil {
+					return err
+				}
+
+				if err := p.MoveTrack(from-1, to-1); err != nil {
+					return err
+				}
+
+				_, err = s.ChannelMessageSend(m.ChannelID, "moved track "+args["from"]+" to position "+args["to"])
+				return err
+			},
+		),
+	)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func NowPlaying() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"now-playing",
+		"<now playing|np>",
+		"shows the track being played and how far along it is",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*(?:now\s*playing|np)\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, _ map[string]string) error {
+
+				np, ok := p.NowPlaying()
+				if !ok {
+					_, err := s.ChannelMessageSend(m.Message.ChannelID, "# nothing is playing")
+					return err
+				}
+
+				meta := np.Track.Metadata()
+
+				msg := "---\n#\n# now playing:\n#\n\n" +
+					"title: " + np.Track.Title() + "\n"
+
+				if meta.Channel != "" {
+					msg += "channel: " + meta.Channel + "\n"
+				}
+
+				msg += "url: `" + np.Track.SrcUrlStr() + "`\n"
+
+				if np.Track.AuthorMention != "" {
+					msg += "from: " + np.Track.AuthorMention + "\n"
+				}
+
+				if np.Track.Live() {
+					msg += "position: " + service.FormatDuration(np.Position) + " / LIVE\n"
+				} else if total, ok := np.Track.Duration(); ok {
+					msg += "position: " + service.FormatDuration(np.Position) + " / " + service.FormatDuration(total) + "\n" +
+						"progress: `" + progressBar(np.Position, total) + "`\n"
+				} else {
+					msg += "position: " + service.FormatDuration(np.Position) + "\n"
+				}
+
+				if np.Paused {
+					msg += "state: paused\n"
+				} else {
+					msg += "state: playing\n"
+				}
+
+				msg += "repeat_mode: " + np.RepeatMode.String() + "\n" +
+					"shuffle_mode: " + np.ShuffleMode + "\n"
+
+				_, err := s.ChannelMessageSend(m.Message.ChannelID, msg)
+				return err
+			},
+		),
+	)
+}
+
+// progressBar renders how far pos is through total
+func progressBar(pos, total time.Duration) string {
+	const width = 20
+
+	n := 0
+	if total > 0 {
+		n = int(int64(width) * int64(pos) / int64(total))
+	}
+	if n < 0 {
+		n = 0
+	} else if n >= width {
+		n = width - 1
+	}
+
+	return "[" + strings.Repeat("=", n) + ">" + strings.Repeat("-", width-n-1) + "]"
+}
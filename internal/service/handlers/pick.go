@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Pick() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"pick",
+		"pick <n>",
+		"appends the n-th result of your most recent search to the playlist",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*pick\s+(?P<n>\d+)\s*$`),
+			func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				n, err := strconv.Atoi(args["n"])
+				if err != nil {
+					return err
+				}
+
+				r, err := p.SearchResult(m.Author.ID, n)
+				if err != nil {
+					return err
+				}
+
+				return playWithPlacement(ctx, s, m, p, r.URL, service.Placement{})
+			},
+		),
+	)
+}
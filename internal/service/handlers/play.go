@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -40,9 +39,11 @@ var (
 )
 
 type MediaMetaCacheEntry struct {
-	VideoID string         `json:"video_id"`
-	Format  youtube.Format `json:"format"`
-	Size    int64          `json:"size"`
+	VideoID  string                `json:"video_id"`
+	Format   youtube.Format        `json:"format"`
+	Size     int64                 `json:"size"`
+	Loudness *service.Loudness     `json:"loudness,omitempty"`
+	Metadata service.TrackMetadata `json:"metadata"`
 }
 
 var vidMetadataCacheOptions = []cache.DiskCacheOption[string, MediaMetaCacheEntry]{
@@ -95,8 +96,8 @@ func Play() HandleMessageCreate {
 
 	return newHandleMessageCreate(
 		"play",
-		"play <url>",
-		"append track from youtube url to the playlist",
+		"play <url|search terms>",
+		"append track from youtube url, discord attachment url, audio file or live radio stream url, library:<id>, or the best youtube search match, to the playlist; a t= url parameter starts playback at that time",
 		newRegexMatcher(
 			true,
 			regexp.MustCompile(`^\s*play\s+(?P<url>[^\s]+.*?)\s*$`),
@@ -105,16 +106,71 @@ func Play() HandleMessageCreate {
 	)
 }
 
+// playContext records the playlist and player state a stream was requested for
+type playContext struct {
+	pid  service.PlaylistID
+	pslc time.Time // player state last changed
+}
+
+func (pc *playContext) setPlayContext(pid service.PlaylistID, pslc time.Time) {
+	pc.pid = pid
+	pc.pslc = pslc
+}
+
+func (pc *playContext) PlaylistID() string {
+	return pc.pid.String()
+}
+
+func (pc *playContext) PlayerStateLastChangedAt() time.Time {
+	return pc.pslc
+}
+
+// playableStream is an audio stream that can be added to a playlist by a play request
+type playableStream interface {
+	service.AudioStreamer
+	setPlayContext(service.PlaylistID, time.Time)
+}
+
 type audioStream struct {
-	pid              service.PlaylistID
-	pslc             time.Time // player state last changed
+	playContext
+	guildID          string
+	tm               *service.TranscodeManager
 	srcVideoUrlStr   string
 	size             int64
 	ytApiClient      *youtube.Client
 	ytDownloadClient *youtube.Client
 	*youtube.Video
 	*youtube.Format
-	dstFilePath string
+	dstFilePath  string
+	transcodeSem chan struct{}
+	meta         service.TrackMetadata
+}
+
+func newAudioStream(p *service.Player, urlStr string, ac *youtube.Client) *audioStream {
+	return &audioStream{
+		guildID:          p.GuildID(),
+		tm:               p.TranscodeManager(),
+		srcVideoUrlStr:   urlStr,
+		ytApiClient:      ac,
+		ytDownloadClient: newYoutubeDownloadClient(),
+		transcodeSem:     make(chan struct{}, 1),
+	}
+}
+
+// lockTranscode ensures only one download and transcode of the stream happens at a time
+//
+// The returned func releases the lock.
+func (as *audioStream) lockTranscode(ctx context.Context) (func(), error) {
+	ctxDone := ctx.Done()
+	select {
+	case <-ctxDone:
+		return nil, ctx.Err()
+	case as.transcodeSem <- struct{}{}:
+	}
+
+	return func() {
+		<-as.transcodeSem
+	}, nil
 }
 
 type flushedState struct {
@@ -167,14 +223,6 @@ func (rc *readCloser) Close() error {
 	return rc.close()
 }
 
-func (as *audioStream) PlaylistID() string {
-	return as.pid.String()
-}
-
-func (as *audioStream) PlayerStateLastChangedAt() time.Time {
-	return as.pslc
-}
-
 func (as *audioStream) SrcUrlStr() string {
 	return as.srcVideoUrlStr
 }
@@ -203,6 +251,23 @@ func (as *audioStream) SelectDownloadURLWithFallbackApiClient(ctx context.Contex
 		as.size = cacheV.Size
 		fmt := cacheV.Format
 		as.Format = &fmt
+		as.meta = cacheV.Metadata
+
+		if as.meta.Title == "" {
+			// entry was cached before track metadata was recorded
+			if v, err := as.ytApiClient.GetVideoContext(ctx, as.srcVideoUrlStr); err == nil {
+				as.meta = newTrackMetadata(v)
+				cacheV.Metadata = as.meta
+
+				if err := vidMetadataCache.Set(as.srcVideoUrlStr, cacheV); err != nil {
+					logging.Context(ctx).ErrorContext(ctx,
+						"failed to update a video metadata cache entry",
+						"error", err,
+						"key", as.srcVideoUrlStr,
+					)
+				}
+			}
+		}
 	} else {
 
 		ytVid, err = as.ytApiClient.GetVideoContext(ctx, as.srcVideoUrlStr)
@@ -217,6 +282,8 @@ func (as *audioStream) SelectDownloadURLWithFallbackApiClient(ctx context.Contex
 		formats := ytVid.Formats.Type("video/mp4")
 		formats.Sort()
 
+		as.meta = newTrackMetadata(ytVid)
+
 		ytVid = &youtube.Video{ID: ytVid.ID}
 
 		for i := len(formats) - 1; i >= 0; i-- {
@@ -292,9 +359,10 @@ func (as *audioStream) SelectDownloadURLWithFallbackApiClient(ctx context.Contex
 
 	if !cacheHit {
 		cacheV = MediaMetaCacheEntry{
-			VideoID: ytVid.ID,
-			Format:  *as.Format,
-			Size:    as.size,
+			VideoID:  ytVid.ID,
+			Format:   *as.Format,
+			Size:     as.size,
+			Metadata: as.meta,
 		}
 
 		if err := vidMetadataCache.Set(as.srcVideoUrlStr, cacheV); err != nil {
@@ -358,316 +426,147 @@ func (as *audioStream) Cached() bool {
 	return true
 }
 
-//nolint:gocyclo
-func (as *audioStream) ReadCloser(ctx context.Context, wg *sync.WaitGroup) (io.ReadCloser, error) {
+func (as *audioStream) Metadata() service.TrackMetadata {
+	return as.meta
+}
 
-	if as.Cached() {
-		logging.Context(ctx).DebugContext(ctx,
-			"playing from cache",
-			"url", as.srcVideoUrlStr,
-			"cached_file", as.dstFilePath,
-		)
-		return os.Open(as.dstFilePath)
+func newTrackMetadata(v *youtube.Video) service.TrackMetadata {
+	result := service.TrackMetadata{
+		Title:    v.Title,
+		Channel:  v.Author,
+		Duration: v.Duration,
 	}
 
-	logging.Context(ctx).DebugContext(ctx,
-		"transcoding just in time and playing from transcode activity",
-		"url", as.srcVideoUrlStr,
-	)
-
-	var errResp struct {
-		sync.RWMutex
-		err error
+	var width uint
+	for _, t := range v.Thumbnails {
+		if t.URL != "" && t.Width >= width {
+			width = t.Width
+			result.ThumbnailURL = t.URL
+		}
 	}
 
-	setErr := func(err error) {
-		if err == nil {
-			return
-		}
+	return result
+}
 
-		errResp.Lock()
-		defer errResp.Unlock()
+// Duration is measured from the transcoded file once cached, otherwise it is estimated from the selected format
+func (as *audioStream) Duration() (time.Duration, bool) {
 
-		if errResp.err != nil {
-			return
+	if as.dstFilePath != "" {
+		if info, err := os.Stat(as.dstFilePath); err == nil && info.Size() > 0 {
+			return service.BytesToDuration(info.Size()), true
 		}
-
-		logging.Context(ctx).ErrorContext(ctx,
-			"error in audio stream read-closer",
-			"error", err,
-			"src_url", as.srcVideoUrlStr,
-			"dst_path", as.dstFilePath,
-			"size", as.size,
-		)
-
-		errResp.err = err
 	}
 
-	getErr := func() error {
-		errResp.RLock()
-		defer errResp.RUnlock()
-
-		return errResp.err
+	if as.meta.Duration > 0 {
+		return as.meta.Duration, true
 	}
 
-	cacheDir := path.Dir(as.dstFilePath)
-
-	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
-		return nil, fmt.Errorf("failed to make cache directory: %s: %w", cacheDir, err)
+	if as.Format == nil || as.Format.ApproxDurationMs == "" {
+		return 0, false
 	}
 
-	tmpF, err := os.CreateTemp(cacheDir, "melody-bot.*.audio.s16le.tmp")
+	ms, err := strconv.ParseInt(as.Format.ApproxDurationMs, 10, 64)
 	if err != nil {
-		return nil, err
+		return 0, false
 	}
 
-	tmpFilePath := tmpF.Name()
-
-	ignoredErr := tmpF.Close()
-	_ = ignoredErr
-
-	var teeDst io.WriteCloser
-	var teeOK bool
-	var fileCreateTried bool
+	return time.Duration(ms) * time.Millisecond, true
+}
 
-	tmpFilePathCleanup := func() {
-		defer os.Remove(tmpFilePath)
+// ReadCloser plays the audio stream while it is downloaded and transcoded to the media cache
+//
+// Only the download and transcode run on the transcode manager, the returned
+// file is read outside of it so playback never holds a worker.
+func (as *audioStream) ReadCloser(ctx context.Context, wg *sync.WaitGroup) (io.ReadCloser, error) {
 
-		if f := teeDst; f != nil {
-			teeDst = nil
-			f.Close()
-		}
+	if as.Cached() {
+		logging.Context(ctx).DebugContext(ctx,
+			"playing from cache",
+			"url", as.srcVideoUrlStr,
+			"cached_file", as.dstFilePath,
+		)
+		return os.Open(as.dstFilePath)
 	}
 
-	pr, pw := io.Pipe()
-	ctx, cancel := context.WithCancel(ctx)
-	result := readCloser{
-		close: func() error {
-			defer cancel()
-			defer func() {
-				if tmpFilePathCleanup == nil {
-					return
-				}
-
-				logger := slog.Default()
-				if err := getErr(); err != nil {
-					logger = logger.With("error", err)
-				}
+	logging.Context(ctx).DebugContext(ctx,
+		"transcoding just in time and playing from transcode activity",
+		"url", as.srcVideoUrlStr,
+	)
 
-				logger.Debug(
-					"could not cache audio stream",
-					"src_url", as.srcVideoUrlStr,
-					"dst_path", as.dstFilePath,
-				)
-
-				tmpFilePathCleanup()
-			}()
-			pr.Close()
-			return nil
-		},
-	}
+	tf, ctx := service.NewTranscodingFile(ctx)
 
 	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		defer cancel()
+	err := as.tm.Enqueue(ctx, service.TranscodeRequest{
+		GuildID:     as.guildID,
+		Key:         as.srcVideoUrlStr,
+		Description: "play " + as.srcVideoUrlStr,
+		Priority:    service.TranscodePriorityNowPlaying,
+		Run: func(ctx context.Context) {
+			defer wg.Done()
 
-		defer pw.Close()
+			err := as.transcodeToCache(ctx, tf.Start)
+			tf.Finish(err)
 
-		slog.Debug(
-			"getting stream",
-			"content-length", as.Format.ContentLength,
-			"video-url", as.srcVideoUrlStr,
-		)
-
-		f, s, err := as.getStream(ctx, as.Video, as.Format)
-		if err != nil {
-			setErr(fmt.Errorf("failed to get stream: %w", err))
-			return
-		}
-		defer func() {
-			if f != nil {
-				f.Close()
-			}
-		}()
-		if s == 0 {
-			if v := f; v != nil {
-				f = nil
-				v.Close()
-			}
-
-			as.Video = nil
-			as.size = 0
-			as.Format = nil
-
-			if err := vidMetadataCache.Delete(as.srcVideoUrlStr); err != nil {
-				setErr(fmt.Errorf("failed to delete from metadata cache: %w", err))
-				return
-			}
-
-			if err := as.SelectDownloadURL(ctx); err != nil {
-				setErr(fmt.Errorf("failed to select download url: %w", err))
-				return
-			}
-			f, s, err = as.getStream(ctx, as.Video, as.Format)
 			if err != nil {
-				setErr(fmt.Errorf("failed to get stream a second time: %w", err))
-				return
-			}
-			if s == 0 {
-				setErr(errors.New("failed to get stream context"))
-				return
-			}
-		}
-
-		cr := newCountingReader(f)
-
-		if s != as.size {
-			setErr(fmt.Errorf("unexpected stream size detected on open, expected %d, got %d", as.size, s))
-			return
-		}
-
-		cmd := exec.CommandContext(ctx, "ffmpeg", "-f", "mp4", "-y", "-loglevel", "quiet", "-i", "pipe:", "-ar", strconv.Itoa(service.SampleRate), "-ac", "1", "-vn", "-f", "s16le", "pipe:1")
-		cmd.Stdin = cr
-		bw := bufio.NewWriter(pw)
-		cmd.Stdout = bw
-		defer bw.Flush()
-
-		if err := cmd.Run(); err != nil {
-			setErr(fmt.Errorf("stream conversion process failed: %w", err))
-			return
-		}
-
-		if cr.bytesRead != as.size {
-			err := errors.New("unexpected end of stream during transcode+reader")
-
-			slog.Error(
-				"failed to download all bytes from source stream for transcode+reader",
-				"error", err,
-				"src_url", as.srcVideoUrlStr,
-				"dst_path", as.dstFilePath,
-				"bytes_read", cr.bytesRead,
-				"size", as.size,
-			)
-
-			setErr(err)
-			return
-		}
-
-		result.setFlushed()
-	}()
-
-	result.read = func(b []byte) (int, error) {
-		if err := getErr(); err != nil {
-			return 0, err
-		}
-
-		if teeDst == nil && !fileCreateTried {
-			fileCreateTried = true
-
-			if f, err := os.Create(tmpFilePath); err != nil {
-				slog.Error(
-					"failed to open file for writing",
-					"error", err,
-					"file", tmpFilePath,
-				)
-			} else {
-				teeDst = f
-				teeOK = true
-			}
-		}
-
-		i, err := pr.Read(b)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-
-				// fully wait for writer to return
-				<-ctx.Done()
-
-				// double check there was no error in the transcode activity
-				if err := getErr(); err != nil {
-					return 0, err
-				}
-
-				// handle tee file state
-				if renameErr := func() error {
-					if !teeOK {
-						return nil
-					}
-
-					if i > 0 {
-						if _, err := teeDst.Write(b[:i]); err != nil {
-							slog.Error(
-								"failed to write to tee tmp file near end of transcode",
-								"error", err,
-							)
-							teeOK = false
-							return nil
-						}
-					}
-
-					f := teeDst
-					teeDst = nil  // don't let defer try to close it again
-					teeOK = false // don't attempt to write to tee again
-					if err := f.Close(); err != nil {
-						slog.Error(
-							"failed to close tee tmp file near end of transcode",
-							"error", err,
-						)
-						return nil
-					}
-
-					if err := os.Rename(tmpFilePath, as.dstFilePath); err != nil {
-						slog.Error(
-							"failed to rename file",
-							"error", err,
-							"src", tmpFilePath,
-							"dst", as.dstFilePath,
-						)
-						return err
-					}
-
-					// tmp file is now gone, don't try to remove it
-					tmpFilePathCleanup = nil
-
-					slog.Debug(
-						"cached audio stream",
+				if ctx.Err() == nil {
+					logging.Context(ctx).ErrorContext(ctx,
+						"error in audio stream read-closer",
+						"error", err,
 						"src_url", as.srcVideoUrlStr,
 						"dst_path", as.dstFilePath,
+						"size", as.size,
 					)
-
-					return nil
-				}(); renameErr != nil {
-					return i, renameErr
 				}
+				return
 			}
 
-			cancel()
-
-			return i, err
-		}
-
-		if teeOK && i > 0 {
-			if _, err := teeDst.Write(b[:i]); err != nil {
-				teeOK = false
-				slog.Error(
-					"failed to append to tee tmp file",
-					"error", err,
-				)
-			}
-		}
-
-		return i, nil
+			as.scheduleLoudnessAnalysis(ctx, wg)
+		},
+	})
+	if err != nil {
+		wg.Done()
+		tf.Close()
+		return nil, fmt.Errorf("failed to schedule transcode: %w", err)
 	}
 
-	return &result, nil
+	return tf, nil
 }
 
-// DownloadAndTranscode synchronously downloads and transcodes the audio stream to disk
+// DownloadAndTranscode synchronously downloads and transcodes the audio stream to disk and measures its loudness
 //
 // The audio stream should be considered closed after a call is made to this function
 // and it cannot be mixed with the async ReadCloser func.
 func (as *audioStream) DownloadAndTranscode(ctx context.Context) error {
+	if err := as.transcodeToCache(ctx, nil); err != nil {
+		return err
+	}
+
+	if _, ok := as.Loudness(); !ok {
+		as.analyzeLoudness(ctx, as.dstFilePath)
+	}
+
+	return nil
+}
+
+// transcodeToCache downloads and transcodes the audio stream to the media cache unless it is already cached
+//
+// When started is not nil it is given the file being written before the
+// transcode begins, or the cached file when there is nothing to do.
+func (as *audioStream) transcodeToCache(ctx context.Context, started func(filePath string) error) error {
+
+	unlock, err := as.lockTranscode(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if as.Cached() {
+		if started != nil {
+			return started(as.dstFilePath)
+		}
+
+		return nil
+	}
 
 	slog.Debug(
 		"downloading and transcoding to cache",
@@ -700,6 +599,12 @@ func (as *audioStream) DownloadAndTranscode(ctx context.Context) error {
 		}
 	}()
 
+	if started != nil {
+		if err := started(tmpFilePath); err != nil {
+			return err
+		}
+	}
+
 	slog.Debug(
 		"getting stream",
 		"content-length", as.Format.ContentLength,
@@ -747,7 +652,7 @@ func (as *audioStream) DownloadAndTranscode(ctx context.Context) error {
 		return fmt.Errorf("unexpected stream size detected on open, expected %d, got %d", as.size, s)
 	}
 
-	cmd := exec.CommandContext(ctx, "ffmpeg", "-f", "mp4", "-y", "-loglevel", "quiet", "-i", "pipe:", "-ar", strconv.Itoa(service.SampleRate), "-ac", "1", "-vn", "-f", "s16le", tmpFilePath)
+	cmd := transcodeCommand(ctx, "mp4", "pipe:", tmpFilePath)
 	cmd.Stdin = cr
 
 	if err := cmd.Run(); err != nil {
@@ -784,6 +689,20 @@ func (as *audioStream) DownloadAndTranscode(ctx context.Context) error {
 	return nil
 }
 
+// transcodeCommand converts the media at input to the player's s16le format and writes it to output
+//
+// An empty inputFormat lets ffmpeg detect the container format, which requires
+// input to be seekable for some formats such as mp4.
+func transcodeCommand(ctx context.Context, inputFormat, input, output string) *exec.Cmd {
+	args := []string{"-y", "-loglevel", "quiet"}
+	if inputFormat != "" {
+		args = append(args, "-f", inputFormat)
+	}
+	args = append(args, "-i", input, "-ar", strconv.Itoa(service.SampleRate), "-ac", "1", "-vn", "-f", "s16le", output)
+
+	return exec.CommandContext(ctx, "ffmpeg", args...)
+}
+
 var apiHttpClient = http.Client{
 	Timeout: 10 * time.Second,
 }
@@ -805,26 +724,33 @@ func newYoutubeDownloadClient() *youtube.Client {
 }
 
 func handlePlayRequest(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+	return playWithPlacement(ctx, s, m, p, args["url"], service.Placement{})
+}
+
+// playWithPlacement adds the track or playlist at urlStr to the playlist at the given placement
+func playWithPlacement(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, urlStr string, placement service.Placement) error {
 	pid := p.PlaylistID()
 	pslc := p.StateLastChangedAt()
 
-	urlStr := args["url"]
-
 	playPack := make(chan service.PlayCall, 1)
 
 	p.Enqueue(playPack)
 
-	var play func(ctx context.Context, as *audioStream)
+	// only applies to single tracks, not playlists
+	var startOffset time.Duration
+
+	var play func(ctx context.Context, as playableStream)
 	{
 		mention := m.Author.Mention()
-		play = func(ctx context.Context, as *audioStream) {
-			as.pid = pid
-			as.pslc = pslc
+		play = func(ctx context.Context, as playableStream) {
+			as.setPlayContext(pid, pslc)
 			pc := service.PlayCall{
 				MessageCreate: m,
 				AuthorID:      m.Message.Author.ID,
 				AuthorMention: mention,
 				AudioStreamer: as,
+				StartOffset:   startOffset,
+				Placement:     placement,
 			}
 
 			ctxDone := ctx.Done()
@@ -860,52 +786,88 @@ func handlePlayRequest(ctx context.Context, s *discordgo.Session, m *discordgo.M
 		}
 	}()
 
-	if processPlaylist(ctx, s, m, p, play, closePlayPack, urlStr) {
+	// text no source understands, other than a url, is searched for
+	src, err := findAudioSource(urlStr)
+	if err != nil && service.IsSearchQuery(urlStr) {
+		r, serr := service.BestSearchResult(ctx, p.Searcher(), urlStr)
+		if serr != nil {
+			closePlayPack()
+			return fmt.Errorf("failed to search for %q: %w", urlStr, serr)
+		}
+
+		if _, err := s.ChannelMessageSend(m.ChannelID, "found: "+r.Title+" ( <"+r.URL+"> )"); err != nil {
+			closePlayPack()
+			return err
+		}
+
+		urlStr = r.URL
+		src, err = findAudioSource(urlStr)
+	}
+	if err != nil {
+		closePlayPack()
+		return err
+	}
+
+	if src.isCollection() {
 		// handling async, don't close the play package
+		playCollection(ctx, s, m, p, play, closePlayPack, src, urlStr)
 		return nil
 	}
 
 	defer closePlayPack()
 
-	return playAfterTranscode(ctx, s, m, p, play, urlStr)
-}
-
-var ErrPanicInPlaylistLoader = errors.New("panic in playlist loader")
+	if src.timestamped {
+		urlStr, startOffset = splitStartOffset(urlStr)
+	}
 
-//nolint:gocyclo
-func processPlaylist(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, play func(context.Context, *audioStream), closePlayPack func(), urlStr string) bool {
-	var result bool
+	return playTrack(ctx, s, m, p, play, src, urlStr)
+}
 
-	ac := newYoutubeApiClient()
+// splitStartOffset removes the youtube start time parameter from a url so the
+// url can be used as a cache key and returns the start time separately
+//
+// The other query parameters keep their order and encoding so the url still
+// matches the same url played without a start time.
+func splitStartOffset(urlStr string) (string, time.Duration) {
+	u, err := url.Parse(urlStr)
+	if err != nil || u == nil || u.RawQuery == "" {
+		return urlStr, 0
+	}
 
-	var u *url.URL
-	{
-		v, err := url.Parse(urlStr)
-		if err != nil || v == nil {
-			logging.Context(ctx).DebugContext(ctx,
-				"failed to parse url argument",
-				"url", urlStr,
-			)
-			return result
+	var t string
+	var kept []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		k, v, _ := strings.Cut(pair, "=")
+		if key, err := url.QueryUnescape(k); err != nil || key != "t" {
+			kept = append(kept, pair)
+			continue
 		}
 
-		u = v
+		if t == "" {
+			t, _ = url.QueryUnescape(v)
+		}
 	}
 
-	if u.Path == "" || !strings.HasPrefix(u.Path, "/") {
-		u.Path = "/" + u.Path
+	if t == "" {
+		return urlStr, 0
 	}
 
-	if u.Path != "/playlist" && u.Path != "/playlist/" {
-		logging.Context(ctx).DebugContext(ctx,
-			"not a playlist url",
-			"url", urlStr,
-		)
-		return result
+	d, err := parseTimestamp(t)
+	if err != nil {
+		return urlStr, 0
 	}
 
-	// take ownership of the request handling context:
-	result = true
+	u.RawQuery = strings.Join(kept, "&")
+
+	return u.String(), d
+}
+
+var ErrPanicInPlaylistLoader = errors.New("panic in playlist loader")
+
+// playCollection adds the tracks of a collection, such as a playlist, to the playlist in the background
+//
+// It takes ownership of closing the play package.
+func playCollection(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, play func(context.Context, playableStream), closePlayPack func(), src *audioSource, urlStr string) {
 
 	var extCancel *func(error)
 	var cancel func(error)
@@ -964,49 +926,36 @@ func processPlaylist(ctx context.Context, s *discordgo.Session, m *discordgo.Mes
 				return err
 			}
 
-			pl, err := ac.GetPlaylistContext(ctx, urlStr)
+			trackUrls, err := src.tracks(ctx, urlStr)
 			if err != nil {
-				return fmt.Errorf("failed to download playlist: %w", err)
+				return err
 			}
 
 			// TODO: cache playlist video urls for playlist url string
 
-			// ensure that the bot is first in a voice channel
-			{
-				_, err := findVoiceChannel(s, m, p)
-				if err != nil {
-					return fmt.Errorf("failed to auto-join a voice channel: %w", err)
-				}
-			}
-
-			if !p.HasAudience() {
-				return errors.New("no audience in voice channel")
+			if err := ensureAudience(s, m, p); err != nil {
+				return err
 			}
 
-			if len(pl.Videos) == 0 {
-				return errors.New("youtube playlist was empty")
+			if len(trackUrls) == 0 {
+				return errors.New(src.name + " was empty")
 			}
 
 			var numFailed, numSuccess int
-			for _, v := range pl.Videos {
+			for _, trackUrl := range trackUrls {
 				if err := ctx.Err(); err != nil {
 					return err
 				}
 
-				as := &audioStream{
-					srcVideoUrlStr:   fmt.Sprintf("https://www.youtube.com/watch?v=%s", url.QueryEscape(v.ID)),
-					ytApiClient:      ac,
-					ytDownloadClient: newYoutubeDownloadClient(),
-				}
-
-				if err := as.SelectDownloadURL(ctx); err != nil {
+				as, err := resolveTrack(ctx, p, trackUrl)
+				if err != nil {
 					logging.Context(ctx).ErrorContext(ctx,
-						"failed to select download url",
+						"failed to resolve track",
 						"error", err,
-						"track", as.srcVideoUrlStr,
+						"track", trackUrl,
 					)
 
-					p.BroadcastTextMessage("Failed to queue " + as.srcVideoUrlStr)
+					p.BroadcastTextMessage("Failed to queue " + trackUrl)
 
 					numFailed++
 					continue
@@ -1031,39 +980,50 @@ func processPlaylist(ctx context.Context, s *discordgo.Session, m *discordgo.Mes
 			return nil
 		}()
 	}()
-
-	return result
 }
 
-func playAfterTranscode(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, play func(context.Context, *audioStream), urlStr string) error {
+// playTrack resolves a single track url with its source and adds it to the playlist
+func playTrack(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, play func(context.Context, playableStream), src *audioSource, urlStr string) error {
 
-	if urlStr == "" {
-		return nil
+	if err := ensureAudience(s, m, p); err != nil {
+		return err
 	}
 
-	// ensure that the bot is first in a voice channel
-	{
-		_, err := findVoiceChannel(s, m, p)
-		if err != nil {
-			return fmt.Errorf("failed to auto-join a voice channel: %w", err)
-		}
+	as, err := src.stream(ctx, p, urlStr)
+	if err != nil {
+		return err
 	}
 
-	if !p.HasAudience() {
-		return errors.New("no audience in voice channel")
+	play(ctx, as)
+
+	return nil
+}
+
+// resolveTrack finds the source of a track url and resolves it to a stream
+func resolveTrack(ctx context.Context, p *service.Player, urlStr string) (playableStream, error) {
+	src, err := findAudioSource(urlStr)
+	if err != nil {
+		return nil, err
 	}
 
-	as := &audioStream{
-		srcVideoUrlStr:   urlStr,
-		ytApiClient:      newYoutubeApiClient(),
-		ytDownloadClient: newYoutubeDownloadClient(),
+	if src.isCollection() {
+		return nil, fmt.Errorf("%s is not a single track: %s", src.name, urlStr)
 	}
 
-	if err := as.SelectDownloadURL(ctx); err != nil {
-		return err
+	return src.stream(ctx, p, urlStr)
+}
+
+// ensureAudience makes sure the bot is in a voice channel with someone to play to
+func ensureAudience(s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player) error {
+
+	// ensure that the bot is first in a voice channel
+	if _, err := findVoiceChannel(s, m, p); err != nil {
+		return fmt.Errorf("failed to auto-join a voice channel: %w", err)
 	}
 
-	play(ctx, as)
+	if !p.HasAudience() {
+		return errors.New("no audience in voice channel")
+	}
 
 	return nil
 }
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func PlayNext() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"play-next",
+		"play next <url|search terms>",
+		"adds a track, youtube playlist or search match to play next, behind earlier play next requests",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*play\s+next\s+(?P<url>[^\s]+.*?)\s*$`),
+			func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+				return playWithPlacement(ctx, s, m, p, args["url"], service.Placement{Next: true})
+			},
+		),
+	)
+}
@@ -0,0 +1,35 @@
+package handlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service/handlers"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSplitStartOffset(t *testing.T) {
+	Convey("the t= parameter is removed from a url and returned as its start offset", t, func() {
+		cases := []struct {
+			name   string
+			url    string
+			want   string
+			offset time.Duration
+		}{
+			{"the only parameter", "https://youtu.be/dQw4w9WgXcQ?t=90", "https://youtu.be/dQw4w9WgXcQ", 90 * time.Second},
+			{"after another parameter", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1m30s", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", 90 * time.Second},
+			{"between unsorted parameters", "https://example.com/song.mp3?b=1&t=90&a=2", "https://example.com/song.mp3?b=1&a=2", 90 * time.Second},
+			{"with other parameters left encoded as given", "https://example.com/song.mp3?b=x%20y&a=z+w&t=90", "https://example.com/song.mp3?b=x%20y&a=z+w", 90 * time.Second},
+			{"missing", "https://example.com/song.mp3?b=1&a=2", "https://example.com/song.mp3?b=1&a=2", 0},
+			{"not a timestamp", "https://example.com/song.mp3?b=1&t=soon", "https://example.com/song.mp3?b=1&t=soon", 0},
+		}
+
+		for _, c := range cases {
+			Convey(c.name, func() {
+				urlStr, offset := handlers.SplitStartOffset(c.url)
+				So(urlStr, ShouldEqual, c.want)
+				So(offset, ShouldEqual, c.offset)
+			})
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Prefetch() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"prefetch",
+		fmt.Sprintf("prefetch <0-%d>", service.MaxPrefetchCount),
+		"sets how many upcoming tracks are downloaded in the background while a track plays",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*prefetch\s+(?P<count>\d+)\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				n, err := strconv.Atoi(args["count"])
+				if err != nil {
+					return err
+				}
+
+				if err := p.SetPrefetchCount(n); err != nil {
+					return err
+				}
+
+				_, err = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("prefetch count is now: %d", n))
+				return err
+			},
+		),
+	)
+}
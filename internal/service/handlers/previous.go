@@ -12,7 +12,7 @@ func Previous() HandleMessageCreate {
 	return newHandleMessageCreate(
 		"previous",
 		"<previous|prev>",
-		"move playback to the previous track in the playlist",
+		"move playback to the track that played before the current one",
 		newWordMatcher(
 			true,
 			[]string{"previous", "prev"},
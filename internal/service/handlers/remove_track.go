@@ -2,36 +2,95 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"net/url"
 	"regexp"
+	"strconv"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/josephcopenhaver/melody-bot/internal/service"
+	"github.com/kkdai/youtube/v2"
+)
+
+const removeDuplicatesArg = "duplicates"
+
+var (
+	removeRangeRegexp  = regexp.MustCompile(`^(?P<from>\d+)(?:\s*-\s*(?P<to>\d+))?$`)
+	removeAuthorRegexp = regexp.MustCompile(`^from\s+<@!?(?P<user_id>\d+)>$`)
 )
 
 func RemoveTrack() HandleMessageCreate {
 
 	return newHandleMessageCreate(
 		"remove-track",
-		"remove <track_url>",
-		"removes a track from the playlist",
+		"remove <track_url|position|from-to|from @user|duplicates>",
+		"removes tracks from the playlist by url, 1-based position, position range, requester, or removes repeated videos",
 		newRegexMatcher(
 			true,
-			regexp.MustCompile(`^\s*remove\s+(?P<track_url>[^\s]+.*?)\s*$`),
+			regexp.MustCompile(`^\s*remove\s+(?P<target>[^\s]+.*?)\s*$`),
 			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
 
-				url := args["track_url"]
+				target := args["target"]
 
-				if url == "" {
+				if target == "" {
 					return nil
 				}
 
-				removed := p.RemoveTrack(url)
-
 				var msg string
-				if removed {
-					msg += "track removed: `" + url + "`"
-				} else {
-					msg += "track not found: `" + url + "`"
+
+				switch {
+				case target == removeDuplicatesArg:
+					var playing string
+					if np, ok := p.NowPlaying(); ok {
+						playing = np.Track.SrcUrlStr()
+					}
+
+					removed := p.RemoveTracks(duplicateTracks(playing))
+
+					msg = "duplicate tracks removed: " + strconv.Itoa(len(removed))
+				case removeAuthorRegexp.MatchString(target):
+					userID := removeAuthorRegexp.FindStringSubmatch(target)[removeAuthorRegexp.SubexpIndex("user_id")]
+
+					removed := p.RemoveTracks(func(_ int, t service.Track) bool {
+						return t.AuthorId == userID
+					})
+
+					msg = "tracks removed: " + strconv.Itoa(len(removed))
+				case removeRangeRegexp.MatchString(target):
+					match := removeRangeRegexp.FindStringSubmatch(target)
+
+					from, err := strconv.Atoi(match[removeRangeRegexp.SubexpIndex("from")])
+					if err != nil {
+						return err
+					}
+
+					to := from
+					if v := match[removeRangeRegexp.SubexpIndex("to")]; v != "" {
+						to, err = strconv.Atoi(v)
+						if err != nil {
+							return err
+						}
+					}
+
+					if from < 1 || to < from {
+						return errors.New("invalid track position range: " + target)
+					}
+
+					removed := p.RemoveTracks(func(i int, _ service.Track) bool {
+						return i+1 >= from && i+1 <= to
+					})
+
+					if len(removed) == 0 {
+						msg = "no tracks at position " + target
+					} else {
+						msg = "tracks removed: " + strconv.Itoa(len(removed))
+					}
+				default:
+					if p.RemoveTrack(target) {
+						msg = "track removed: `" + target + "`"
+					} else {
+						msg = "track not found: `" + target + "`"
+					}
 				}
 
 				_, err := s.ChannelMessageSend(m.Message.ChannelID, msg)
@@ -40,3 +99,42 @@ func RemoveTrack() HandleMessageCreate {
 		),
 	)
 }
+
+// duplicateTracks returns a RemoveTracks match func that matches every track of a video after its first
+//
+// The track with the url playing is never matched, instead every other track of its video is.
+func duplicateTracks(playing string) func(int, service.Track) bool {
+	seen := map[string]struct{}{}
+	if playing != "" {
+		seen[videoKey(playing)] = struct{}{}
+	}
+
+	return func(_ int, t service.Track) bool {
+		if playing != "" && t.SrcUrlStr() == playing {
+			return false
+		}
+
+		k := videoKey(t.SrcUrlStr())
+		if _, ok := seen[k]; ok {
+			return true
+		}
+
+		seen[k] = struct{}{}
+		return false
+	}
+}
+
+// videoKey identifies the video a url refers to so differently formatted urls of the same video compare equal
+//
+// Only youtube urls are reduced to their video id, the id extraction also accepts other urls.
+func videoKey(urlStr string) string {
+	if u, err := url.Parse(urlStr); err != nil || !isYoutubeHost(u.Host) {
+		return urlStr
+	}
+
+	if id, err := youtube.ExtractVideoID(urlStr); err == nil {
+		return id
+	}
+
+	return urlStr
+}
@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"testing"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	"github.com/josephcopenhaver/melody-bot/internal/service/handlers"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type urlStreamer struct {
+	service.AudioStreamer
+	url string
+}
+
+func (us urlStreamer) SrcUrlStr() string {
+	return us.url
+}
+
+func TestRemoveDuplicates(t *testing.T) {
+	Convey("every url of a video after the first one in the playlist is a duplicate", t, func() {
+		urls := []string{
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			"https://youtu.be/dQw4w9WgXcQ",
+			"https://www.youtube.com/watch?v=9bZkp7q19f0",
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42",
+			"https://example.com/song.mp3",
+			"https://youtu.be/9bZkp7q19f0?si=shared",
+			"https://example.com/song.mp3?v=2",
+		}
+
+		match := handlers.DuplicateTracks("")
+
+		var duplicates []string
+		for i, url := range urls {
+			if match(i, service.Track{AudioStreamer: urlStreamer{url: url}}) {
+				duplicates = append(duplicates, url)
+			}
+		}
+
+		So(duplicates, ShouldResemble, []string{
+			"https://youtu.be/dQw4w9WgXcQ",
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42",
+			"https://youtu.be/9bZkp7q19f0?si=shared",
+		})
+	})
+
+	Convey("the playing track is kept when it is not the first url of its video", t, func() {
+		urls := []string{
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			"https://example.com/song.mp3",
+			"https://youtu.be/dQw4w9WgXcQ",
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42",
+		}
+
+		match := handlers.DuplicateTracks("https://youtu.be/dQw4w9WgXcQ")
+
+		var duplicates []string
+		for i, url := range urls {
+			if match(i, service.Track{AudioStreamer: urlStreamer{url: url}}) {
+				duplicates = append(duplicates, url)
+			}
+		}
+
+		So(duplicates, ShouldResemble, []string{
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42",
+		})
+	})
+}
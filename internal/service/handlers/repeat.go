@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/josephcopenhaver/melody-bot/internal/service"
@@ -11,14 +12,25 @@ func Repeat() HandleMessageCreate {
 
 	return newHandleMessageCreate(
 		"repeat",
-		"repeat",
-		"cycles playlist repeat mode between [\"repeating\", \"not repeating\"]",
-		newWordMatcher(
+		"repeat [off|all|one]",
+		"cycles repeat mode between [\"off\", \"all\", \"one\"] or sets it; \"all\" repeats the playlist and \"one\" repeats the current track",
+		newRegexMatcher(
 			true,
-			[]string{"repeat"},
-			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player) error {
+			regexp.MustCompile(`^\s*repeat(?:\s+(?P<mode>off|all|one))?\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				var repeatMode string
+
+				if v := args["mode"]; v != "" {
+					r, err := service.ParseRepeatMode(v)
+					if err != nil {
+						return err
+					}
 
-				repeatMode := p.CycleRepeatMode()
+					repeatMode = p.SetRepeatMode(r)
+				} else {
+					repeatMode = p.CycleRepeatMode()
+				}
 
 				_, err := s.ChannelMessageSend(m.ChannelID, "repeat mode is now: "+repeatMode)
 				return err
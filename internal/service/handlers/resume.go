@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/josephcopenhaver/melody-bot/internal/service"
@@ -12,11 +13,16 @@ func Resume() HandleMessageCreate {
 	return newHandleMessageCreate(
 		"resume",
 		"<resume|unpause|play>",
-		"if stopped or paused, resumes playback",
-		newWordMatcher(
+		"if stopped or paused, resumes playback; play with attached audio or video files appends them to the playlist",
+		newRegexMatcher(
 			true,
-			[]string{"resume", "unpause", "play"},
-			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player) error {
+			regexp.MustCompile(`^\s*(?P<cmd>resume|unpause|play)\s*$`),
+			func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				// only play takes attachments, resume and unpause ignore them
+				if args["cmd"] == "play" && len(m.Attachments) > 0 {
+					return playAttachments(ctx, s, m, p)
+				}
 
 				p.Resume(m)
 
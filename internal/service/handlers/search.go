@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	"github.com/josephcopenhaver/melody-bot/internal/service/server/reactions"
+)
+
+const searchPlayCustomIDPrefix = "search:play:"
+
+func Search() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"search",
+		"search <terms>",
+		"lists the top youtube matches for the terms; play one with its button or with pick",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*search\s+(?P<terms>.+?)\s*$`),
+			func(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				results, err := p.Searcher().Search(ctx, args["terms"], service.SearchResultLimit)
+				if err != nil {
+					return err
+				}
+
+				if len(results) == 0 {
+					return service.ErrNoSearchResults
+				}
+
+				return sendSearchResults(s, m, p, results)
+			},
+		),
+	)
+}
+
+// sendSearchResults lists search results with a button to play each of them and remembers them for pick
+func sendSearchResults(s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, results []service.SearchResult) error {
+	p.SetSearchResults(m.Author.ID, results)
+
+	var sb strings.Builder
+	var buttons []discordgo.MessageComponent
+	for i, r := range results {
+		line := fmt.Sprintf("`%d.` %s", i+1, r.Title)
+
+		if r.Channel != "" {
+			line += " - " + r.Channel
+		}
+
+		if r.Duration > 0 {
+			line += " `" + service.FormatDuration(r.Duration) + "`"
+		}
+
+		sb.WriteString(line + "\n")
+
+		buttons = append(buttons, discordgo.Button{
+			Label:    strconv.Itoa(i + 1),
+			Style:    discordgo.SecondaryButton,
+			CustomID: searchPlayCustomIDPrefix + r.URL,
+		})
+	}
+
+	sb.WriteString("\nplay one with a button or `pick <n>`")
+
+	_, err := s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: sb.String(),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: buttons},
+		},
+	})
+	return err
+}
+
+// SearchPlayButton adds the search result of a clicked button to the playlist
+func SearchPlayButton() HandleMessageComponent {
+
+	return newHandleMessageComponent(
+		"search-play-button",
+		searchPlayCustomIDPrefix,
+		func(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, p *service.Player, urlStr string) error {
+
+			if i.Member == nil || i.Member.User == nil {
+				return errors.New("button was not clicked by a guild member")
+			}
+
+			// adding a track can take longer than discord waits for a response
+			err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			})
+			if err != nil {
+				return err
+			}
+
+			m := &discordgo.MessageCreate{
+				Message: &discordgo.Message{
+					ID:        i.ID,
+					ChannelID: i.ChannelID,
+					GuildID:   i.GuildID,
+					Content:   "play " + urlStr,
+					Author:    i.Member.User,
+					Member:    i.Member,
+					Timestamp: time.Now(),
+				},
+			}
+
+			content := reactions.StatusOK.String() + " " + i.Member.User.Mention() + " picked <" + urlStr + ">"
+			if err := playWithPlacement(ctx, s, m, p, urlStr, service.Placement{}); err != nil {
+				content = reactions.StatusErr.String() + " failed to play <" + urlStr + ">\nerror: " + err.Error()
+			}
+
+			_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content})
+			return err
+		},
+	)
+}
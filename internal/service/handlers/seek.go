@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Seek() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"seek",
+		"seek <mm:ss>",
+		"moves playback of the current track to a position from the start of the track",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*seek\s+(?P<position>[^\s]+)\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				pos, err := parseTimestamp(args["position"])
+				if err != nil {
+					return err
+				}
+
+				p.Seek(m, pos)
+
+				return nil
+			},
+		),
+	)
+}
+
+// parseTimestamp parses [[h:]m:]s timestamps as well as durations like 1m30s
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return 0, fmt.Errorf("invalid timestamp: %q", s)
+		}
+
+		return d, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp: %q", s)
+	}
+
+	var result time.Duration
+	for i, v := range parts {
+		n, err := strconv.ParseUint(v, 10, 31)
+		if err != nil || (i > 0 && n >= 60) {
+			return 0, fmt.Errorf("invalid timestamp: %q", s)
+		}
+
+		result = result*60 + time.Duration(n)*time.Second
+	}
+
+	return result, nil
+}
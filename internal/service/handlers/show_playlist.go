@@ -2,22 +2,37 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/josephcopenhaver/melody-bot/internal/service"
 )
 
+const (
+	playlistPageSize           = 10
+	playlistMaxTitleLen        = 80
+	playlistPageCustomIDPrefix = "show-playlist:page:"
+	playlistEmbedColor         = 0x5865f2
+	playlistCurrentTrackMark   = "▶"
+
+	// maxEmbedDescriptionLen is the most characters discord accepts in an embed description
+	maxEmbedDescriptionLen = 4096
+)
+
 func ShowPlaylist() HandleMessageCreate {
 
 	return newHandleMessageCreate(
 		"show-playlist",
-		"show playlist",
-		"prints the current playlist",
+		"show playlist [page]",
+		"prints a page of the current playlist, by default the page with the current track",
 		newRegexMatcher(
 			true,
-			regexp.MustCompile(`^\s*show\s*playlist\s*$`),
-			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, _ map[string]string) error {
+			regexp.MustCompile(`^\s*show\s*playlist(?:\s+(?P<page>\d+))?\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
 
 				playlist := p.GetPlaylist()
 
@@ -26,21 +41,175 @@ func ShowPlaylist() HandleMessageCreate {
 					return err
 				}
 
-				msg := "---\n#\n# playlist:\n#\n"
-
-				for i, t := range playlist.Tracks {
-					msg += "\n- url: `" + t.SrcUrlStr() + "`\n" +
-						"  from: " + t.AuthorMention + "\n"
-					if i == playlist.CurrentTrackIdx {
-						msg += "  state: playing\n"
-					} else {
-						msg += "  state: queued\n"
+				page := -1
+				if v := args["page"]; v != "" {
+					n, err := strconv.Atoi(v)
+					if err != nil {
+						return err
 					}
+
+					page = n - 1
 				}
 
-				_, err := s.ChannelMessageSend(m.Message.ChannelID, msg)
+				embed, components := playlistPage(playlist, page)
+
+				_, err := s.ChannelMessageSendComplex(m.Message.ChannelID, &discordgo.MessageSend{
+					Embeds:     []*discordgo.MessageEmbed{embed},
+					Components: components,
+				})
 				return err
 			},
 		),
 	)
 }
+
+// ShowPlaylistPage turns the pages of a playlist message when its navigation buttons are clicked
+func ShowPlaylistPage() HandleMessageComponent {
+
+	return newHandleMessageComponent(
+		"show-playlist-page",
+		playlistPageCustomIDPrefix,
+		func(_ context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, p *service.Player, args string) error {
+
+			page, err := strconv.Atoi(args)
+			if err != nil {
+				return err
+			}
+
+			playlist := p.GetPlaylist()
+
+			data := &discordgo.InteractionResponseData{
+				Content:    "# no tracks in playlist",
+				Embeds:     []*discordgo.MessageEmbed{},
+				Components: []discordgo.MessageComponent{},
+			}
+
+			if len(playlist.Tracks) > 0 {
+				embed, components := playlistPage(playlist, page)
+
+				data.Content = ""
+				data.Embeds = []*discordgo.MessageEmbed{embed}
+				data.Components = components
+			}
+
+			return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseUpdateMessage,
+				Data: data,
+			})
+		},
+	)
+}
+
+// playlistHiddenLine notes the number of tracks of a page left out of its description
+func playlistHiddenLine(n int) string {
+	return fmt.Sprintf("… and %d more tracks on this page\n", n)
+}
+
+// playlistPage renders one page of a non-empty playlist as an embed with navigation buttons
+//
+// A negative page selects the page with the current track. Pages are 0-based and
+// clamped to the pages that exist.
+func playlistPage(playlist service.Playlist, page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	numPages := (len(playlist.Tracks) + playlistPageSize - 1) / playlistPageSize
+
+	if page < 0 {
+		page = 0
+		if playlist.CurrentTrackIdx > 0 {
+			page = playlist.CurrentTrackIdx / playlistPageSize
+		}
+	}
+	if page >= numPages {
+		page = numPages - 1
+	}
+
+	var total time.Duration
+	var numUnknown int
+	var hasLive bool
+	for i := range playlist.Tracks {
+		if playlist.Tracks[i].Live() {
+			hasLive = true
+			continue
+		}
+
+		d, ok := playlist.Tracks[i].Duration()
+		if !ok {
+			numUnknown++
+			continue
+		}
+
+		total += d
+	}
+
+	var sb strings.Builder
+
+	start := page * playlistPageSize
+	end := min(start+playlistPageSize, len(playlist.Tracks))
+	for i := start; i < end; i++ {
+		t := &playlist.Tracks[i]
+
+		line := fmt.Sprintf("`%d.` [%s](%s)", i+1, t.LinkText(playlistMaxTitleLen), t.SrcUrlStr())
+
+		if d, ok := t.DurationString(); ok {
+			line += " `" + d + "`"
+		}
+
+		if t.AuthorMention != "" {
+			line += " " + t.AuthorMention
+		}
+
+		if i == playlist.CurrentTrackIdx {
+			line = playlistCurrentTrackMark + " **" + line + "**"
+		}
+		line += "\n"
+
+		// long urls can fill the description before the page does
+		if sb.Len()+len(line)+len(playlistHiddenLine(playlistPageSize)) > maxEmbedDescriptionLen {
+			sb.WriteString(playlistHiddenLine(end - i))
+			break
+		}
+
+		sb.WriteString(line)
+	}
+
+	totalStr := service.FormatDuration(total)
+	if numUnknown > 0 {
+		totalStr += "+"
+	}
+	if hasLive {
+		totalStr += " + LIVE"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "playlist",
+		Description: sb.String(),
+		Color:       playlistEmbedColor,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("page %d/%d • %d tracks • %s total • repeat: %s", page+1, numPages, len(playlist.Tracks), totalStr, playlist.RepeatMode.String()),
+		},
+	}
+
+	if numPages <= 1 {
+		return embed, []discordgo.MessageComponent{}
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "previous page",
+					Style:    discordgo.SecondaryButton,
+					Disabled: page == 0,
+					CustomID: playlistPageCustomIDPrefix + strconv.Itoa(page-1),
+				},
+				discordgo.Button{
+					Label:    "next page",
+					Style:    discordgo.SecondaryButton,
+					Disabled: page == numPages-1,
+					CustomID: playlistPageCustomIDPrefix + strconv.Itoa(page+1),
+				},
+			},
+		},
+	}
+
+	return embed, components
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Shuffle() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"shuffle",
+		"shuffle [on|off]",
+		"without arguments randomizes the order of the tracks after the current one; \"on\" plays tracks in a random order until the whole playlist has been heard",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*shuffle(?:\s+(?P<mode>on|off))?\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				var msg string
+
+				switch args["mode"] {
+				case "on":
+					msg = "shuffle mode is now: " + p.SetShuffleMode(true)
+				case "off":
+					msg = "shuffle mode is now: " + p.SetShuffleMode(false)
+				default:
+					n := p.ShuffleUpcoming()
+					if n == 0 {
+						msg = "no upcoming tracks to shuffle"
+					} else {
+						msg = "shuffled " + strconv.Itoa(n) + " upcoming tracks"
+					}
+				}
+
+				_, err := s.ChannelMessageSend(m.ChannelID, msg)
+				return err
+			},
+		),
+	)
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// discord limits for application commands
+const (
+	slashNameMaxLen        = 32
+	slashDescriptionMaxLen = 100
+)
+
+var (
+	usageWordsRegexp = regexp.MustCompile(`^[a-z]+(?: [a-z]+)*$`)
+	usageRangeRegexp = regexp.MustCompile(`^(\d+)-(\d+)$`)
+	slashNameRegexp  = regexp.MustCompile(`[^a-z0-9_-]+`)
+)
+
+// usageToken is a literal word or a <required> or [optional] placeholder of a usage string
+type usageToken struct {
+	literal  string
+	alts     []string
+	optional bool
+}
+
+func parseUsage(usage string) []usageToken {
+	var result []usageToken
+
+	for s := strings.TrimSpace(usage); s != ""; s = strings.TrimSpace(s) {
+		var end byte
+		switch s[0] {
+		case '<':
+			end = '>'
+		case '[':
+			end = ']'
+		}
+
+		if end == 0 {
+			word, rest, _ := strings.Cut(s, " ")
+			result = append(result, usageToken{literal: word})
+			s = rest
+			continue
+		}
+
+		i := strings.IndexByte(s, end)
+		if i == -1 {
+			i = len(s)
+		}
+
+		result = append(result, usageToken{
+			alts:     strings.Split(s[1:i], "|"),
+			optional: end == ']',
+		})
+
+		s = s[min(i+1, len(s)):]
+	}
+
+	// a leading group of words is a set of aliases for the command, the first one is used
+	if len(result) > 0 && result[0].literal == "" && !result[0].optional {
+		aliases := true
+		for _, v := range result[0].alts {
+			if !usageWordsRegexp.MatchString(v) {
+				aliases = false
+				break
+			}
+		}
+
+		if aliases {
+			result[0] = usageToken{literal: result[0].alts[0]}
+		}
+	}
+
+	return result
+}
+
+func slashName(s string) string {
+	s = slashNameRegexp.ReplaceAllString(strings.ToLower(s), "-")
+	s = strings.Trim(s, "-")
+
+	if len(s) > slashNameMaxLen {
+		s = s[:slashNameMaxLen]
+	}
+
+	return s
+}
+
+func slashDescription(s string) string {
+	if r := []rune(s); len(r) > slashDescriptionMaxLen {
+		s = string(r[:slashDescriptionMaxLen-1]) + "…"
+	}
+
+	if s == "" {
+		s = "-"
+	}
+
+	return s
+}
+
+// slashOption derives a typed application command option from a usage placeholder
+func slashOption(t usageToken) *discordgo.ApplicationCommandOption {
+	result := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Description: slashDescription(strings.Join(t.alts, "|")),
+		Required:    !t.optional,
+	}
+
+	if len(t.alts) > 1 {
+		result.Name = slashName(t.alts[0])
+
+		if !t.optional {
+			return result
+		}
+
+		for _, v := range t.alts {
+			if !usageWordsRegexp.MatchString(v) || strings.Contains(v, " ") {
+				return result
+			}
+		}
+
+		// an optional set of words is a set of keywords to choose from
+		result.Name = "mode"
+		for _, v := range t.alts {
+			result.Choices = append(result.Choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  v,
+				Value: v,
+			})
+		}
+
+		return result
+	}
+
+	v := t.alts[0]
+	result.Name = slashName(v)
+
+	minOne := 1.0
+	minZero := 0.0
+
+	switch v {
+	case "url", "track_url":
+		result.Name = "url"
+	case "channel_name":
+		result.Name = "channel"
+		result.Type = discordgo.ApplicationCommandOptionChannel
+		result.ChannelTypes = []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice}
+	case "mm:ss":
+		result.Name = "timestamp"
+	case "position", "from", "to", "a", "b", "page":
+		result.Type = discordgo.ApplicationCommandOptionInteger
+		result.MinValue = &minOne
+	case "secs":
+		result.Type = discordgo.ApplicationCommandOptionInteger
+		result.MinValue = &minZero
+	default:
+		if m := usageRangeRegexp.FindStringSubmatch(v); m != nil {
+			lo, _ := strconv.ParseFloat(m[1], 64)
+			hi, _ := strconv.ParseFloat(m[2], 64)
+
+			result.Name = "value"
+			result.Type = discordgo.ApplicationCommandOptionInteger
+			result.MinValue = &lo
+			result.MaxValue = hi
+		}
+	}
+
+	return result
+}
+
+// SlashCommand derives an application command from the handler's name, usage, and description
+func (h *HandleMessageCreate) SlashCommand() *discordgo.ApplicationCommand {
+	result := &discordgo.ApplicationCommand{
+		Name:        slashName(h.Name),
+		Description: slashDescription(h.Description),
+	}
+
+	for _, t := range parseUsage(h.Usage) {
+		if t.literal != "" {
+			continue
+		}
+
+		result.Options = append(result.Options, slashOption(t))
+	}
+
+	return result
+}
+
+// SlashCommandText rebuilds the text command a slash command invocation stands for
+//
+// The text can be passed to the handler's Matcher like any other message.
+func (h *HandleMessageCreate) SlashCommandText(s *discordgo.Session, data discordgo.ApplicationCommandInteractionData) string {
+
+	values := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(data.Options))
+	for _, v := range data.Options {
+		values[v.Name] = v
+	}
+
+	var parts []string
+	for _, t := range parseUsage(h.Usage) {
+		if t.literal != "" {
+			parts = append(parts, t.literal)
+			continue
+		}
+
+		opt := slashOption(t)
+
+		v, ok := values[opt.Name]
+		if !ok {
+			continue
+		}
+
+		switch v.Type {
+		case discordgo.ApplicationCommandOptionInteger:
+			parts = append(parts, strconv.FormatInt(v.IntValue(), 10))
+		case discordgo.ApplicationCommandOptionChannel:
+			if c := v.ChannelValue(s); c != nil {
+				parts = append(parts, c.Name)
+			}
+		default:
+			parts = append(parts, v.StringValue())
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+var ErrNoAudioSource = errors.New("no audio source understands the url")
+
+// audioSource provides audio streams for the urls it claims
+//
+// A source either resolves a url to a single track with stream, or lists the
+// urls of the tracks in a collection, such as a playlist, with tracks. The
+// urls of a collection are resolved by whichever sources claim them.
+type audioSource struct {
+	name string
+	// claims reports if the source understands the url
+	claims func(u *url.URL) bool
+	stream func(ctx context.Context, p *service.Player, urlStr string) (playableStream, error)
+	tracks func(ctx context.Context, urlStr string) ([]string, error)
+	// timestamped sources honor a t= url parameter as the position to start playback at
+	timestamped bool
+}
+
+func (src *audioSource) isCollection() bool {
+	return src.tracks != nil
+}
+
+// audioSources are consulted in order, the first source to claim a url provides its streams
+var audioSources = []audioSource{
+	youtubePlaylistSource(),
+	youtubeVideoSource(),
+	attachmentSource(),
+	librarySource(),
+	httpSource(),
+}
+
+func findAudioSource(urlStr string) (*audioSource, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil || u == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoAudioSource, urlStr)
+	}
+
+	for i := range audioSources {
+		src := &audioSources[i]
+
+		if src.claims(u) {
+			return src, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNoAudioSource, urlStr)
+}
+
+func isYoutubeHost(host string) bool {
+	host = strings.ToLower(host)
+
+	switch host {
+	case "youtube.com", "youtu.be", "youtube-nocookie.com", "www.youtube-nocookie.com":
+		return true
+	}
+
+	return strings.HasSuffix(host, ".youtube.com")
+}
+
+func youtubePlaylistSource() audioSource {
+	return audioSource{
+		name: "youtube-playlist",
+		claims: func(u *url.URL) bool {
+			return isYoutubeHost(u.Host) && strings.TrimSuffix(u.Path, "/") == "/playlist"
+		},
+		tracks: func(ctx context.Context, urlStr string) ([]string, error) {
+			pl, err := newYoutubeApiClient().GetPlaylistContext(ctx, urlStr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to download playlist: %w", err)
+			}
+
+			result := make([]string, 0, len(pl.Videos))
+			for _, v := range pl.Videos {
+				result = append(result, "https://www.youtube.com/watch?v="+url.QueryEscape(v.ID))
+			}
+
+			return result, nil
+		},
+	}
+}
+
+func youtubeVideoSource() audioSource {
+	return audioSource{
+		name: "youtube-video",
+		claims: func(u *url.URL) bool {
+			return isYoutubeHost(u.Host)
+		},
+		stream: func(ctx context.Context, p *service.Player, urlStr string) (playableStream, error) {
+			as := newAudioStream(p, urlStr, newYoutubeApiClient())
+
+			if err := as.SelectDownloadURLWithFallbackApiClient(ctx, newYoutubeApiClient); err != nil {
+				return nil, err
+			}
+
+			return as, nil
+		},
+		timestamped: true,
+	}
+}
+
+func attachmentSource() audioSource {
+	return audioSource{
+		name: "attachment",
+		claims: func(u *url.URL) bool {
+			return isAttachmentURL(u)
+		},
+		stream: func(_ context.Context, p *service.Player, urlStr string) (playableStream, error) {
+			return newAttachmentStream(p, urlStr)
+		},
+	}
+}
+
+func librarySource() audioSource {
+	return audioSource{
+		name: "library",
+		claims: func(u *url.URL) bool {
+			return isLibraryURL(u)
+		},
+		stream: func(_ context.Context, p *service.Player, urlStr string) (playableStream, error) {
+			return newLibraryStream(p, urlStr)
+		},
+	}
+}
+
+// httpSource claims any remaining http(s) url, which must refer to an audio file or a live audio stream
+func httpSource() audioSource {
+	return audioSource{
+		name: "http",
+		claims: func(u *url.URL) bool {
+			return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+		},
+		stream: func(ctx context.Context, p *service.Player, urlStr string) (playableStream, error) {
+			probe, err := probeHTTPAudio(ctx, urlStr)
+			if err != nil {
+				return nil, err
+			}
+
+			if probe.live {
+				return newLiveStream(urlStr, probe), nil
+			}
+
+			return newHTTPStream(p, urlStr, probe), nil
+		},
+	}
+}
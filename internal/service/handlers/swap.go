@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Swap() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"swap",
+		"swap <a> <b>",
+		"exchanges the playlist positions of two tracks, 1 being the first track",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*swap\s+(?P<a>\d+)\s+(?P<b>\d+)\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				a, err := strconv.Atoi(args["a"])
+				if err != nil {
+					return err
+				}
+
+				b, err := strconv.Atoi(args["b"])
+				if err != nil {
+					return err
+				}
+
+				if err := p.SwapTracks(a-1, b-1); err != nil {
+					return err
+				}
+
+				_, err = s.ChannelMessageSend(m.ChannelID, "swapped tracks "+args["a"]+" and "+args["b"])
+				return err
+			},
+		),
+	)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func TranscodeStatus() HandleMessageCreate {
+
+	return newHandleMessageCreateWithBrain(
+		"transcode-status",
+		"transcode status",
+		"shows how busy the download and transcode workers are",
+		newRegexMatcherWithBrain(
+			false,
+			regexp.MustCompile(`^\s*transcode\s*status\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, _ *service.Player, _ map[string]string, b *service.Brain) error {
+
+				status := b.TranscodeManager().Status()
+				now := time.Now()
+
+				msg := "---\n#\n# transcode status:\n#\n\n" +
+					fmt.Sprintf("workers_busy: %d/%d\n", len(status.Running), status.NumWorkers) +
+					fmt.Sprintf("queued: %d/%d\n", len(status.Queued), status.MaxQueueSize)
+
+				// only describe the jobs of the guild asking, other guilds just count toward the totals
+
+				const maxQueuedListed = 10
+
+				var running, queued []string
+				var numQueued int
+				for _, j := range status.Running {
+					if m.GuildID == "" || j.GuildID != m.GuildID {
+						continue
+					}
+
+					running = append(running, "\n- task: `"+j.Description+"`\n"+
+						"  priority: "+j.Priority.String()+"\n"+
+						"  running_for: "+now.Sub(j.StartedAt).Round(time.Second).String()+"\n")
+				}
+
+				for _, j := range status.Queued {
+					if m.GuildID == "" || j.GuildID != m.GuildID {
+						continue
+					}
+
+					numQueued++
+					if numQueued > maxQueuedListed {
+						continue
+					}
+
+					queued = append(queued, "\n- task: `"+j.Description+"`\n"+
+						"  priority: "+j.Priority.String()+"\n"+
+						"  waiting_for: "+now.Sub(j.EnqueuedAt).Round(time.Second).String()+"\n")
+				}
+
+				// leave room for the queued section when listing running jobs
+				maxRunningLen := maxMessageLen
+				if numQueued > 0 {
+					maxRunningLen -= len("\n# queued:\n") + len(moreJobsLine(numQueued))
+				}
+
+				msg = appendJobList(msg, "running", running, len(running), maxRunningLen)
+				msg = appendJobList(msg, "queued", queued, numQueued, maxMessageLen)
+
+				_, err := s.ChannelMessageSend(m.ChannelID, msg)
+				return err
+			},
+		),
+	)
+}
+
+// moreJobsLine notes the number of jobs left out of a job list
+func moreJobsLine(n int) string {
+	return fmt.Sprintf("\n# ... and %d more\n", n)
+}
+
+// appendJobList appends a titled list of total jobs to msg, of which only the first
+// len(jobs) are described
+//
+// Jobs that would make msg longer than maxLen are left out and counted instead.
+func appendJobList(msg, title string, jobs []string, total, maxLen int) string {
+	if total == 0 {
+		return msg
+	}
+
+	msg += "\n# " + title + ":\n"
+
+	// the count of left out jobs never needs more room than the total
+	moreLen := len(moreJobsLine(total))
+
+	for i, j := range jobs {
+		if len(msg)+len(j)+moreLen > maxLen {
+			return msg + moreJobsLine(total-i)
+		}
+
+		msg += j
+	}
+
+	if total > len(jobs) {
+		msg += moreJobsLine(total - len(jobs))
+	}
+
+	return msg
+}
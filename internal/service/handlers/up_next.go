@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func UpNext() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"up-next",
+		"up next [on|off]",
+		"sets if track change broadcasts also say which track plays next ( off by default ), or shows the setting when no mode is given",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*up\s*next(?:\s+(?P<mode>on|off))?\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				verb := "are"
+				if mode := args["mode"]; mode != "" {
+					if err := p.SetAnnounceUpNext(mode == "on"); err != nil {
+						return err
+					}
+
+					verb = "are now"
+				}
+
+				msg := "up next announcements " + verb + ": off"
+				if p.Settings().AnnounceUpNext {
+					msg = "up next announcements " + verb + ": on"
+				}
+
+				_, err := s.ChannelMessageSend(m.ChannelID, msg)
+				return err
+			},
+		),
+	)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+)
+
+func Volume() HandleMessageCreate {
+
+	return newHandleMessageCreate(
+		"volume",
+		fmt.Sprintf("volume [0-%d]", service.MaxVolume),
+		"sets the playback volume as a percentage, or shows it when no level is given",
+		newRegexMatcher(
+			true,
+			regexp.MustCompile(`^\s*volume(?:\s+(?P<level>\d+)\s*%?)?\s*$`),
+			func(_ context.Context, s *discordgo.Session, m *discordgo.MessageCreate, p *service.Player, args map[string]string) error {
+
+				levelStr := args["level"]
+				if levelStr == "" {
+					_, err := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("volume is: %d%%", p.Volume()))
+					return err
+				}
+
+				v, err := strconv.Atoi(levelStr)
+				if err != nil {
+					return err
+				}
+
+				if err := p.SetVolume(v); err != nil {
+					return err
+				}
+
+				_, err = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("volume is now: %d%%", p.Volume()))
+				return err
+			},
+		),
+	)
+}
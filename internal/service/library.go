@@ -0,0 +1,577 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LibraryIndexDir = ".library-index/v1"
+	// LibraryURLScheme prefixes the id of a library track to form its url, as in library:<id>
+	LibraryURLScheme = "library"
+)
+
+var (
+	ErrLibraryDisabled       = errors.New("no library directory is configured")
+	ErrLibraryTrackNotFound  = errors.New("library track not found, it may have been removed by a rescan")
+	ErrLibraryScanInProgress = errors.New("a library scan is already in progress")
+)
+
+// libraryAudioExts are the file extensions a scan considers to be audio files
+var libraryAudioExts = map[string]struct{}{
+	".aac":  {},
+	".aif":  {},
+	".aiff": {},
+	".flac": {},
+	".m4a":  {},
+	".mka":  {},
+	".mp3":  {},
+	".oga":  {},
+	".ogg":  {},
+	".opus": {},
+	".wav":  {},
+	".webm": {},
+	".wma":  {},
+}
+
+// LibraryTags are what is read from an audio file's metadata
+//
+// Fields are empty when unknown.
+type LibraryTags struct {
+	Title    string
+	Artist   string
+	Album    string
+	Duration time.Duration
+}
+
+// LibraryTagReader reads the tags of the audio file at filePath
+type LibraryTagReader func(ctx context.Context, filePath string) (LibraryTags, error)
+
+// LibraryTrack is an indexed audio file of the library
+type LibraryTrack struct {
+	ID string `json:"id"`
+	// Path is relative to the library directory and slash separated
+	Path     string        `json:"path"`
+	Title    string        `json:"title,omitempty"`
+	Artist   string        `json:"artist,omitempty"`
+	Album    string        `json:"album,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Size     int64         `json:"size"`
+	ModTime  time.Time     `json:"mod_time"`
+}
+
+func (t *LibraryTrack) URL() string {
+	return LibraryURLScheme + ":" + t.ID
+}
+
+// Name returns the title of the track, falling back to its file name when the file has no title tag
+func (t *LibraryTrack) Name() string {
+	if t.Title != "" {
+		return t.Title
+	}
+
+	return strings.TrimSuffix(path.Base(t.Path), path.Ext(t.Path))
+}
+
+// Version changes whenever the indexed file changes
+func (t *LibraryTrack) Version() string {
+	return strconv.FormatInt(t.Size, 10) + "-" + strconv.FormatInt(t.ModTime.UnixNano(), 10)
+}
+
+// LibraryScanResult counts what a rescan changed in the index
+type LibraryScanResult struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+	// Failed counts audio files whose tags could not be read, they are not indexed
+	Failed int
+}
+
+// libraryIndex is the persisted form of the library
+type libraryIndex struct {
+	Dir    string         `json:"dir"`
+	Tracks []LibraryTrack `json:"tracks"`
+}
+
+type LibraryOption func(*Library)
+
+// LibraryTagReaderOption replaces reading tags with ffprobe
+func LibraryTagReaderOption(f LibraryTagReader) LibraryOption {
+	return func(l *Library) {
+		l.readTags = f
+	}
+}
+
+// Library indexes the audio files of a local directory so they can be searched and played
+//
+// The index is persisted so the files do not have to be probed again after a
+// restart, a rescan only reads the tags of files that are new or changed.
+type Library struct {
+	rwm           sync.RWMutex
+	dir           string
+	indexFilePath string
+	tracks        map[string]LibraryTrack
+	scanning      atomic.Bool
+	readTags      LibraryTagReader
+}
+
+func NewLibrary(options ...LibraryOption) *Library {
+	l := &Library{
+		tracks:   map[string]LibraryTrack{},
+		readTags: ffprobeTags,
+	}
+
+	for _, f := range options {
+		f(l)
+	}
+
+	return l
+}
+
+// Configure sets the directory of audio files and loads the index persisted in indexDir
+//
+// An empty dir disables the library.
+func (l *Library) Configure(dir, indexDir string) error {
+	l.rwm.Lock()
+	defer l.rwm.Unlock()
+
+	l.dir = ""
+	l.indexFilePath = ""
+	l.tracks = map[string]LibraryTrack{}
+
+	if dir == "" {
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read library directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("library path is not a directory: %s", dir)
+	}
+
+	if err := os.MkdirAll(indexDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to make library index directory: %w", err)
+	}
+
+	l.dir = dir
+	l.indexFilePath = filepath.Join(indexDir, "index.json")
+
+	b, err := os.ReadFile(l.indexFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to read library index: %w", err)
+	}
+
+	var index libraryIndex
+	if err := json.Unmarshal(b, &index); err != nil {
+		slog.Error(
+			"failed to parse library index, it will be rebuilt by the next scan",
+			"error", err,
+			"file", l.indexFilePath,
+		)
+		return nil
+	}
+
+	// an index of some other directory is of no use
+	if index.Dir != dir {
+		return nil
+	}
+
+	for _, t := range index.Tracks {
+		l.tracks[t.ID] = t
+	}
+
+	return nil
+}
+
+func (l *Library) Enabled() bool {
+	l.rwm.RLock()
+	defer l.rwm.RUnlock()
+
+	return l.dir != ""
+}
+
+// Len returns the number of indexed tracks
+func (l *Library) Len() int {
+	l.rwm.RLock()
+	defer l.rwm.RUnlock()
+
+	return len(l.tracks)
+}
+
+func (l *Library) Track(id string) (LibraryTrack, error) {
+	l.rwm.RLock()
+	defer l.rwm.RUnlock()
+
+	if l.dir == "" {
+		return LibraryTrack{}, ErrLibraryDisabled
+	}
+
+	t, ok := l.tracks[id]
+	if !ok {
+		return LibraryTrack{}, fmt.Errorf("%w: %s", ErrLibraryTrackNotFound, id)
+	}
+
+	return t, nil
+}
+
+// FilePath returns where the track's file is on disk
+func (l *Library) FilePath(t LibraryTrack) string {
+	l.rwm.RLock()
+	defer l.rwm.RUnlock()
+
+	return filepath.Join(l.dir, filepath.FromSlash(t.Path))
+}
+
+// Search returns the tracks whose title, artist, album or path contain every word of the query
+//
+// Tracks are ordered by artist, album, title and then path.
+func (l *Library) Search(_ context.Context, query string, limit int) ([]SearchResult, error) {
+	l.rwm.RLock()
+	defer l.rwm.RUnlock()
+
+	if l.dir == "" {
+		return nil, ErrLibraryDisabled
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var matches []LibraryTrack
+	for _, t := range l.tracks {
+		haystack := strings.ToLower(strings.Join([]string{t.Title, t.Artist, t.Album, t.Path}, "\n"))
+
+		matched := true
+		for _, w := range words {
+			if !strings.Contains(haystack, w) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			matches = append(matches, t)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+
+		if v, w := strings.ToLower(a.Artist), strings.ToLower(b.Artist); v != w {
+			return v < w
+		}
+
+		if v, w := strings.ToLower(a.Album), strings.ToLower(b.Album); v != w {
+			return v < w
+		}
+
+		if v, w := strings.ToLower(a.Name()), strings.ToLower(b.Name()); v != w {
+			return v < w
+		}
+
+		return a.Path < b.Path
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	result := make([]SearchResult, 0, len(matches))
+	for _, t := range matches {
+		result = append(result, SearchResult{
+			URL:      t.URL(),
+			Title:    t.Name(),
+			Channel:  t.Artist,
+			Duration: t.Duration,
+		})
+	}
+
+	return result, nil
+}
+
+// Rescan walks the library directory and updates the index to match it
+//
+// Only one scan runs at a time, a concurrent call fails with ErrLibraryScanInProgress.
+func (l *Library) Rescan(ctx context.Context) (LibraryScanResult, error) {
+	var result LibraryScanResult
+
+	if !l.scanning.CompareAndSwap(false, true) {
+		return result, ErrLibraryScanInProgress
+	}
+	defer l.scanning.Store(false)
+
+	var dir string
+	var prev map[string]LibraryTrack
+	func() {
+		l.rwm.RLock()
+		defer l.rwm.RUnlock()
+
+		dir = l.dir
+		prev = l.tracks
+	}()
+
+	if dir == "" {
+		return result, ErrLibraryDisabled
+	}
+
+	next := map[string]LibraryTrack{}
+
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == dir || d == nil {
+				return err
+			}
+
+			// one unreadable entry should not stop the rest of the library from being indexed
+			slog.Warn(
+				"failed to read library entry",
+				"error", err,
+				"path", filePath,
+			)
+
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			result.Failed++
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// hidden files and directories are skipped
+		if filePath != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		if _, ok := libraryAudioExts[strings.ToLower(filepath.Ext(filePath))]; !ok {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			slog.Warn(
+				"failed to read library file info",
+				"error", err,
+				"file", filePath,
+			)
+			result.Failed++
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		t := LibraryTrack{
+			ID:      libraryTrackID(relPath),
+			Path:    relPath,
+			Size:    info.Size(),
+			ModTime: info.ModTime().UTC(),
+		}
+
+		old, existed := prev[t.ID]
+		if existed && old.Version() == t.Version() {
+			next[t.ID] = old
+			result.Unchanged++
+			return nil
+		}
+
+		tags, err := l.readTags(ctx, filePath)
+		if err != nil {
+			if cerr := ctx.Err(); cerr != nil {
+				return cerr
+			}
+
+			slog.Warn(
+				"failed to read library file tags",
+				"error", err,
+				"file", filePath,
+			)
+			result.Failed++
+			return nil
+		}
+
+		t.Title = tags.Title
+		t.Artist = tags.Artist
+		t.Album = tags.Album
+		t.Duration = tags.Duration
+
+		next[t.ID] = t
+		if existed {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to scan library directory: %w", err)
+	}
+
+	for id := range prev {
+		if _, ok := next[id]; !ok {
+			result.Removed++
+		}
+	}
+
+	l.rwm.Lock()
+	defer l.rwm.Unlock()
+
+	// the library was reconfigured during the scan
+	if l.dir != dir {
+		return result, errors.New("library directory changed during the scan")
+	}
+
+	l.tracks = next
+
+	if err := l.saveIndex(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// saveIndex must be called while holding the write lock
+func (l *Library) saveIndex() error {
+	index := libraryIndex{
+		Dir:    l.dir,
+		Tracks: make([]LibraryTrack, 0, len(l.tracks)),
+	}
+
+	for _, t := range l.tracks {
+		index.Tracks = append(index.Tracks, t)
+	}
+
+	sort.Slice(index.Tracks, func(i, j int) bool {
+		return index.Tracks[i].Path < index.Tracks[j].Path
+	})
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	tmpF, err := os.CreateTemp(filepath.Dir(l.indexFilePath), "index.*.json.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save library index: %w", err)
+	}
+	defer os.Remove(tmpF.Name())
+
+	if _, err := tmpF.Write(b); err != nil {
+		tmpF.Close()
+		return fmt.Errorf("failed to save library index: %w", err)
+	}
+
+	if err := tmpF.Close(); err != nil {
+		return fmt.Errorf("failed to save library index: %w", err)
+	}
+
+	if err := os.Rename(tmpF.Name(), l.indexFilePath); err != nil {
+		return fmt.Errorf("failed to save library index: %w", err)
+	}
+
+	return nil
+}
+
+// libraryTrackID derives a stable id from the path of a file so its url survives rescans
+func libraryTrackID(relPath string) string {
+	sum := sha256.Sum256([]byte(relPath))
+	return hex.EncodeToString(sum[:6])
+}
+
+// ffprobeTags reads the tags and duration of an audio file with ffprobe
+func ffprobeTags(ctx context.Context, filePath string) (LibraryTags, error) {
+	var result LibraryTags
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "quiet", "-print_format", "json", "-show_format", "-show_streams", "-select_streams", "a:0", filePath)
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return result, fmt.Errorf("ffprobe process failed: %w", err)
+	}
+
+	var report struct {
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+		Streams []struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		return result, fmt.Errorf("failed to parse ffprobe report: %w", err)
+	}
+
+	if len(report.Streams) == 0 {
+		return result, errors.New("file has no audio stream")
+	}
+
+	// ogg files keep their tags on the stream rather than the container
+	tag := func(name string) string {
+		for _, tags := range []map[string]string{report.Format.Tags, report.Streams[0].Tags} {
+			for k, v := range tags {
+				if strings.EqualFold(k, name) {
+					if v = strings.TrimSpace(v); v != "" {
+						return v
+					}
+				}
+			}
+		}
+
+		return ""
+	}
+
+	result.Title = tag("title")
+	result.Artist = tag("artist")
+	if result.Artist == "" {
+		result.Artist = tag("album_artist")
+	}
+	result.Album = tag("album")
+
+	if v, err := strconv.ParseFloat(report.Format.Duration, 64); err == nil && v > 0 {
+		result.Duration = time.Duration(v * float64(time.Second))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLibrary(t *testing.T) {
+	Convey("a library indexes, searches and rescans the audio files of its directory", t, func() {
+		dir := t.TempDir()
+		indexDir := t.TempDir()
+
+		write := func(relPath, content string) {
+			filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+			So(os.MkdirAll(filepath.Dir(filePath), os.ModePerm), ShouldBeNil)
+			So(os.WriteFile(filePath, []byte(content), 0o644), ShouldBeNil)
+		}
+
+		write("Band/Album/01 First Song.mp3", "1")
+		write("Band/Album/02 Second Song.flac", "22")
+		write("Other/untagged.ogg", "333")
+		write("Other/cover.jpg", "not audio")
+		write(".hidden/secret.mp3", "hidden")
+
+		var numReads atomic.Int32
+		readTags := func(_ context.Context, filePath string) (service.LibraryTags, error) {
+			numReads.Add(1)
+
+			if strings.HasSuffix(filePath, "untagged.ogg") {
+				return service.LibraryTags{}, nil
+			}
+
+			title := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))[3:]
+
+			return service.LibraryTags{
+				Title:    title,
+				Artist:   "Band",
+				Album:    "Album",
+				Duration: time.Minute,
+			}, nil
+		}
+
+		lib := service.NewLibrary(service.LibraryTagReaderOption(readTags))
+		So(lib.Configure(dir, indexDir), ShouldBeNil)
+		So(lib.Enabled(), ShouldBeTrue)
+
+		result, err := lib.Rescan(context.Background())
+		So(err, ShouldBeNil)
+		So(result, ShouldResemble, service.LibraryScanResult{Added: 3})
+		So(lib.Len(), ShouldEqual, 3)
+
+		results, err := lib.Search(context.Background(), "band song", 5)
+		So(err, ShouldBeNil)
+		So(len(results), ShouldEqual, 2)
+		So(results[0].Title, ShouldEqual, "First Song")
+		So(results[0].Channel, ShouldEqual, "Band")
+		So(results[0].Duration, ShouldEqual, time.Minute)
+		So(results[0].URL, ShouldStartWith, "library:")
+
+		// untagged files are named after their file
+		results, err = lib.Search(context.Background(), "UNTAGGED", 5)
+		So(err, ShouldBeNil)
+		So(len(results), ShouldEqual, 1)
+		So(results[0].Title, ShouldEqual, "untagged")
+
+		track, err := lib.Track(strings.TrimPrefix(results[0].URL, "library:"))
+		So(err, ShouldBeNil)
+		So(lib.FilePath(track), ShouldEqual, filepath.Join(dir, "Other", "untagged.ogg"))
+
+		Convey("a rescan only reads the tags of new and changed files", func() {
+			So(os.Remove(filepath.Join(dir, "Other", "untagged.ogg")), ShouldBeNil)
+			write("Band/Album/02 Second Song.flac", "changed")
+			write("Band/Album/03 Third Song.wav", "4444")
+
+			numReads.Store(0)
+			result, err := lib.Rescan(context.Background())
+			So(err, ShouldBeNil)
+			So(result, ShouldResemble, service.LibraryScanResult{Added: 1, Updated: 1, Removed: 1, Unchanged: 1})
+			So(numReads.Load(), ShouldEqual, 2)
+
+			_, err = lib.Track(track.ID)
+			So(errors.Is(err, service.ErrLibraryTrackNotFound), ShouldBeTrue)
+		})
+
+		Convey("the index survives a restart", func() {
+			lib := service.NewLibrary(service.LibraryTagReaderOption(readTags))
+			So(lib.Configure(dir, indexDir), ShouldBeNil)
+			So(lib.Len(), ShouldEqual, 3)
+
+			numReads.Store(0)
+			result, err := lib.Rescan(context.Background())
+			So(err, ShouldBeNil)
+			So(result, ShouldResemble, service.LibraryScanResult{Unchanged: 3})
+			So(numReads.Load(), ShouldEqual, 0)
+		})
+	})
+
+	Convey("a library without a directory is disabled", t, func() {
+		lib := service.NewLibrary()
+		So(lib.Configure("", t.TempDir()), ShouldBeNil)
+		So(lib.Enabled(), ShouldBeFalse)
+
+		_, err := lib.Search(context.Background(), "anything", 5)
+		So(err, ShouldEqual, service.ErrLibraryDisabled)
+
+		_, err = lib.Rescan(context.Background())
+		So(err, ShouldEqual, service.ErrLibraryDisabled)
+	})
+}
+
+func TestLibraryUnreadableDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("directory permissions do not apply to root")
+	}
+
+	Convey("an unreadable directory does not stop the rest of the library from being indexed", t, func() {
+		dir := t.TempDir()
+
+		for _, relPath := range []string{"readable/song.mp3", "unreadable/song.mp3"} {
+			filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+			So(os.MkdirAll(filepath.Dir(filePath), os.ModePerm), ShouldBeNil)
+			So(os.WriteFile(filePath, []byte("1"), 0o644), ShouldBeNil)
+		}
+
+		unreadable := filepath.Join(dir, "unreadable")
+		So(os.Chmod(unreadable, 0), ShouldBeNil)
+		defer os.Chmod(unreadable, os.ModePerm)
+
+		readTags := func(context.Context, string) (service.LibraryTags, error) {
+			return service.LibraryTags{}, nil
+		}
+
+		lib := service.NewLibrary(service.LibraryTagReaderOption(readTags))
+		So(lib.Configure(dir, t.TempDir()), ShouldBeNil)
+
+		result, err := lib.Rescan(context.Background())
+		So(err, ShouldBeNil)
+		So(result, ShouldResemble, service.LibraryScanResult{Added: 1})
+	})
+}
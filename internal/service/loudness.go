@@ -0,0 +1,36 @@
+package service
+
+import (
+	"math"
+)
+
+// loudness normalization targets
+const (
+	LoudnessTargetLUFS      = -16.0
+	LoudnessMaxTruePeakDBTP = -1.0
+)
+
+// Loudness is an EBU R128 measurement of a track
+type Loudness struct {
+	IntegratedLUFS float64 `json:"integrated_lufs"`
+	TruePeakDBTP   float64 `json:"true_peak_dbtp"`
+}
+
+// Gain returns the linear gain that brings a track to the target loudness
+// without pushing its true peak over the max true peak
+func (l Loudness) Gain() float64 {
+	if math.IsInf(l.IntegratedLUFS, 0) || math.IsNaN(l.IntegratedLUFS) {
+		// silence or a broken measurement
+		return 1
+	}
+
+	gainDB := LoudnessTargetLUFS - l.IntegratedLUFS
+
+	if !math.IsInf(l.TruePeakDBTP, 0) && !math.IsNaN(l.TruePeakDBTP) {
+		if headroom := LoudnessMaxTruePeakDBTP - l.TruePeakDBTP; gainDB > headroom {
+			gainDB = headroom
+		}
+	}
+
+	return math.Pow(10, gainDB/20)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// linkTextReplacer keeps a title from ending the text of a markdown link early
+var linkTextReplacer = strings.NewReplacer("[", "(", "]", ")")
+
+// TrackMetadata describes a track to the people listening to it
+//
+// Fields are empty when unknown.
+type TrackMetadata struct {
+	Title        string        `json:"title,omitempty"`
+	Channel      string        `json:"channel,omitempty"`
+	Duration     time.Duration `json:"duration,omitempty"`
+	ThumbnailURL string        `json:"thumbnail_url,omitempty"`
+}
+
+// Title returns the title of the track, falling back to its url when the title is unknown
+func (t *Track) Title() string {
+	if v := t.Metadata().Title; v != "" {
+		return v
+	}
+
+	return t.SrcUrlStr()
+}
+
+// LinkText returns the title of the track for use as the text of a markdown link
+//
+// Brackets are replaced so the link still renders and titles longer than maxLen runes are truncated.
+// Tracks without a title are named after the host and file name of their url.
+func (t *Track) LinkText(maxLen int) string {
+	title := t.Metadata().Title
+	if title == "" {
+		title = shortUrlText(t.SrcUrlStr())
+	}
+
+	if r := []rune(title); len(r) > maxLen {
+		title = string(r[:maxLen-1]) + "…"
+	}
+
+	return linkTextReplacer.Replace(title)
+}
+
+// shortUrlText names a url by its host and the last element of its path
+//
+// Urls that cannot be shortened are returned as is.
+func shortUrlText(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil || u.Host == "" {
+		return urlStr
+	}
+
+	p := strings.Trim(u.Path, "/")
+	if p == "" {
+		return u.Host
+	}
+
+	name := path.Base(p)
+	if v, err := url.PathUnescape(name); err == nil {
+		name = v
+	}
+
+	if !strings.Contains(p, "/") {
+		return u.Host + "/" + name
+	}
+
+	return u.Host + "/…/" + name
+}
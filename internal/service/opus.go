@@ -2,6 +2,9 @@ package service
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
 )
 
 // transcoding constants
@@ -21,3 +24,57 @@ func init() {
 		panic("BytesPerInt16 constant is wrong somehow")
 	}
 }
+
+// applyGain scales each sample by gain, clipping results to the int16 range
+func applyGain(samples []int16, gain float64) {
+	if gain == 1 {
+		return
+	}
+
+	for i, v := range samples {
+		x := math.Round(float64(v) * gain)
+
+		if x > math.MaxInt16 {
+			x = math.MaxInt16
+		} else if x < math.MinInt16 {
+			x = math.MinInt16
+		}
+
+		samples[i] = int16(x)
+	}
+}
+
+// durationToBytes converts a playback duration to an offset in a pcm stream, aligned to the start of a frame
+func durationToBytes(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+
+	numSamples := int64(d) * SampleRate / int64(time.Second)
+	numFrames := numSamples / SampleSize
+
+	return numFrames * SampleMaxBytes
+}
+
+// BytesToDuration converts an offset in a pcm stream to a playback duration
+func BytesToDuration(n int64) time.Duration {
+	numSamples := n / (BytesPerInt16 * NumChannels)
+
+	return time.Duration(numSamples) * time.Second / SampleRate
+}
+
+// FormatDuration renders a duration as m:ss or h:mm:ss
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	secs := int64(d / time.Second)
+	h, m, sec := secs/3600, (secs/60)%60, secs%60
+
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
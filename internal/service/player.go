@@ -1,9 +1,7 @@
 package service
 
 import (
-	"bufio"
 	"context"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -37,6 +35,8 @@ const (
 	SignalNext
 	SignalPrevious
 	SignalRestartTrack
+	SignalSeek
+	SignalJump
 	SignalDispose
 	//
 	SignalUnusedUpper
@@ -53,10 +53,51 @@ func (s Signal) String() string {
 		"next",
 		"previous",
 		"restart-track",
+		"seek",
+		"jump",
 		"dispose",
 	}[int(s)]
 }
 
+// RepeatMode: what plays after the current track ends on its own
+type RepeatMode int8
+
+const (
+	RepeatModeUnusedLower RepeatMode = iota - 1
+	//
+	RepeatModeAll
+	RepeatModeOff
+	RepeatModeOne
+	//
+	RepeatModeUnusedUpper
+)
+
+func (r RepeatMode) String() string {
+	return []string{
+		"all",
+		"off",
+		"one",
+	}[int(r)]
+}
+
+func (r RepeatMode) Description() string {
+	return []string{
+		"repeating playlist",
+		"not repeating playlist",
+		"repeating current track",
+	}[int(r)]
+}
+
+func ParseRepeatMode(s string) (RepeatMode, error) {
+	for r := RepeatModeUnusedLower + 1; r < RepeatModeUnusedUpper; r++ {
+		if r.String() == s {
+			return r, nil
+		}
+	}
+
+	return RepeatModeUnusedLower, fmt.Errorf("unknown repeat mode: %q", s)
+}
+
 type TracedSignal struct {
 	src           interface{}
 	sig           Signal
@@ -87,38 +128,226 @@ func (s State) String() string {
 
 type AudioStreamer interface {
 	ReadCloser(context.Context, *sync.WaitGroup) (io.ReadCloser, error)
+	DownloadAndTranscode(context.Context) error
+	Loudness() (Loudness, bool)
+	Duration() (time.Duration, bool)
+	Metadata() TrackMetadata
 	SrcUrlStr() string
 	Cached() bool
 	PlaylistID() string
 	PlayerStateLastChangedAt() time.Time
 }
 
+// LiveStreamer is implemented by streams that have no end, such as internet radio
+//
+// A live track is never cached, has no duration and only ends when it is skipped
+// or stopped, so the stream must reconnect on its own when it drops.
+type LiveStreamer interface {
+	Live() bool
+}
+
+var ErrLiveStreamNotCacheable = errors.New("live streams cannot be cached")
+
 type Track struct {
 	// public
 	AudioStreamer
 	AuthorId      string
 	AuthorMention string
+	StartOffset   time.Duration
+
+	// private
+	handle *trackHandle
+}
+
+// startPosition returns where playback of the track starts
+//
+// The start offset is only honored the first time the track plays, so repeats,
+// restarts and going back to the track start from the beginning.
+func (t *Track) startPosition() time.Duration {
+	if t.StartOffset <= 0 || t.handle == nil || t.handle.startOffsetUsed.Swap(true) {
+		return 0
+	}
+
+	return t.StartOffset
+}
+
+// Live reports if the track is an endless stream
+func (t *Track) Live() bool {
+	v, ok := t.AudioStreamer.(LiveStreamer)
+	return ok && v.Live()
+}
+
+// DurationString renders the duration of the track, which is LIVE for live tracks
+//
+// Returns false when the duration is unknown.
+func (t *Track) DurationString() (string, bool) {
+	if t.Live() {
+		return "LIVE", true
+	}
+
+	d, ok := t.Duration()
+	if !ok {
+		return "", false
+	}
+
+	return FormatDuration(d), true
+}
+
+// bindContext returns a child context of ctx that is canceled
+// once the track is removed from the playlist
+func (t *Track) bindContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return t.handle.bind(ctx)
+}
+
+var ErrTrackRemoved = errors.New("track removed from playlist")
+
+// trackHandle is shared by all copies of a track
+//
+// It aborts all work bound to the track, such as downloads and transcodes,
+// once the track is removed from the playlist.
+type trackHandle struct {
+	mutex          sync.Mutex
+	cause          error
+	cancelFuncs    map[*context.CancelCauseFunc]struct{}
+	cacheScheduled atomic.Bool
+	// startOffsetUsed is true once the track has started playing at its start offset
+	startOffsetUsed atomic.Bool
+}
+
+func newTrackHandle() *trackHandle {
+	return &trackHandle{
+		cancelFuncs: map[*context.CancelCauseFunc]struct{}{},
+	}
+}
+
+func (th *trackHandle) bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	if th == nil {
+		return ctx, func() { cancel(nil) }
+	}
+
+	th.mutex.Lock()
+	defer th.mutex.Unlock()
+
+	if th.cause != nil {
+		cancel(th.cause)
+		return ctx, func() {}
+	}
+
+	fp := &cancel
+	th.cancelFuncs[fp] = struct{}{}
+
+	return ctx, func() {
+		th.mutex.Lock()
+		delete(th.cancelFuncs, fp)
+		th.mutex.Unlock()
+
+		cancel(nil)
+	}
+}
+
+func (th *trackHandle) cancel(err error) {
+	if th == nil {
+		return
+	}
+
+	var cancelFuncs map[*context.CancelCauseFunc]struct{}
+	func() {
+		th.mutex.Lock()
+		defer th.mutex.Unlock()
+
+		if th.cause != nil {
+			return
+		}
+
+		th.cause = err
+		cancelFuncs = th.cancelFuncs
+		th.cancelFuncs = nil
+	}()
+
+	for f := range cancelFuncs {
+		(*f)(err)
+	}
+}
+
+// cancelTracks aborts all in-flight work of tracks that are no longer in the playlist
+func cancelTracks(tracks []Track) {
+	for i := range tracks {
+		tracks[i].handle.cancel(ErrTrackRemoved)
+	}
+}
+
+type seekRequest struct {
+	position time.Duration
+	relative bool
+	// retry makes a seek past what a just in time transcode has written so far
+	// return ErrSeekAheadOfTranscode instead of telling the user to try again
+	retry bool
 }
 
 type playRequest struct {
 	track      *Track
 	playlistID string
 	pslc       time.Time
+	cursor     *insertCursor
+}
+
+// Placement: where new tracks are added to the playlist
+//
+// The zero value appends tracks to the end of the playlist.
+type Placement struct {
+	// Next places tracks right after the current track
+	Next bool
+	// Position is the 1-based playlist position tracks are inserted at
+	Position int
+}
+
+func (pl Placement) isAppend() bool {
+	return !pl.Next && pl.Position <= 0
+}
+
+// insertCursor keeps the tracks of one play request together and in order when
+// they are inserted rather than appended
+type insertCursor struct {
+	placement Placement
+	// lastUrl is the url of the last track inserted by the play request
+	lastUrl string
 }
 
 type Playlist struct {
 	Tracks          []Track
 	CurrentTrackIdx int
+	RepeatMode      RepeatMode
 }
 
 type PlayerMemory struct {
 	id              uuid.UUID
 	voiceChannelId  string
 	voiceConnection *discordgo.VoiceConnection
-	notLooping      bool
+	repeatMode      RepeatMode
 	currentTrackIdx int
 	textChannel     string
 	tracks          []Track
+
+	// pinnedNext makes the track after currentTrackIdx play next, even when shuffling
+	pinnedNext bool
+
+	// playNextUrl is the url of the last track added by a play next request,
+	// later play next requests add their tracks after it until it starts playing
+	// or playback moves back before it
+	playNextUrl string
+
+	// trackEnded is true when the current track played to its end rather than being skipped
+	trackEnded bool
+
+	shuffle bool
+	// shuffleNext is the url of the track planned to play next when shuffling
+	shuffleNext string
+	// played holds the urls of the tracks played in the current shuffle cycle
+	played map[string]struct{}
+	// history holds the urls of played tracks, oldest first
+	history []string
 }
 
 func (m *PlayerMemory) reset() {
@@ -180,6 +409,11 @@ func (m *PlayerMemory) play(s State, r *playRequest, debug func(string, ...any))
 	case StateIdle:
 		i := m.indexOfTrack(t.SrcUrlStr())
 		if i == -1 {
+			if r.cursor != nil {
+				m.insert(r.cursor, t)
+				return
+			}
+
 			m.tracks = append(m.tracks, t)
 			m.currentTrackIdx = len(m.tracks) - 1
 		}
@@ -187,11 +421,191 @@ func (m *PlayerMemory) play(s State, r *playRequest, debug func(string, ...any))
 	case StatePaused, StatePlaying:
 		i := m.indexOfTrack(t.SrcUrlStr())
 		if i == -1 {
+			if r.cursor != nil {
+				m.insert(r.cursor, t)
+				return
+			}
+
 			m.tracks = append(m.tracks, t)
 		}
 	}
 }
 
+// insert adds a track at the position described by the cursor without changing which track is current
+func (m *PlayerMemory) insert(c *insertCursor, t Track) {
+	i := -1
+
+	if c.lastUrl != "" {
+		if v := m.indexOfTrack(c.lastUrl); v != -1 {
+			i = v + 1
+		}
+	}
+
+	if i == -1 {
+		switch {
+		case c.placement.Next:
+			i = m.currentTrackIdx + 1
+			if m.pinnedNext {
+				// stay behind the track that is about to replay
+				i++
+			}
+
+			// queue behind earlier play next requests so they play in the order they were made
+			if m.playNextUrl != "" {
+				if v := m.indexOfTrack(m.playNextUrl); v >= i {
+					i = v + 1
+				}
+			}
+		case c.placement.Position > 0:
+			i = c.placement.Position - 1
+		default:
+			i = len(m.tracks)
+		}
+	}
+
+	if i < 0 {
+		i = 0
+	} else if i > len(m.tracks) {
+		i = len(m.tracks)
+	}
+
+	m.tracks = append(m.tracks, Track{})
+	copy(m.tracks[i+1:], m.tracks[i:])
+	m.tracks[i] = t
+
+	if i <= m.currentTrackIdx || (m.pinnedNext && i == m.currentTrackIdx+1) {
+		m.currentTrackIdx++
+	}
+
+	c.lastUrl = t.SrcUrlStr()
+
+	if c.placement.Next {
+		m.playNextUrl = c.lastUrl
+
+		if m.shuffle {
+			// the shuffle plan was made before the track was added
+			m.planShuffle()
+		}
+	}
+}
+
+// playNextTrackIdx returns the index of the track after the current track when it
+// was added by a play next request that has not started playing, or -1
+func (m *PlayerMemory) playNextTrackIdx() int {
+	if m.playNextUrl == "" {
+		return -1
+	}
+
+	if m.indexOfTrack(m.playNextUrl) <= m.currentTrackIdx {
+		return -1
+	}
+
+	i := m.currentTrackIdx + 1
+	if m.pinnedNext {
+		i++
+	}
+
+	if i < 0 || i >= len(m.tracks) {
+		return -1
+	}
+
+	return i
+}
+
+// upcomingTracks returns up to n tracks that will play after the current track
+func (m *PlayerMemory) upcomingTracks(n int) []Track {
+	var result []Track
+
+	if m.repeatMode == RepeatModeOne && !m.pinnedNext {
+		if n > 0 && m.currentTrackIdx >= 0 && m.currentTrackIdx < len(m.tracks) {
+			result = append(result, m.tracks[m.currentTrackIdx])
+		}
+
+		return result
+	}
+
+	if m.shuffle && !m.pinnedNext {
+		// only the next pick of a shuffle is known ahead of time
+		if n > 0 {
+			if i := m.indexOfTrack(m.shuffleNext); i != -1 {
+				result = append(result, m.tracks[i])
+			}
+		}
+
+		return result
+	}
+
+	for i := 1; i <= n && i < len(m.tracks); i++ {
+		idx := m.currentTrackIdx + i
+		if idx >= len(m.tracks) {
+			if m.repeatMode == RepeatModeOff {
+				break
+			}
+			idx -= len(m.tracks)
+		}
+
+		result = append(result, m.tracks[idx])
+	}
+
+	return result
+}
+
+// upNext returns the track that plays after the current track, if any, and how many
+// tracks remain after the current one before the playlist ends or repeats
+func (m *PlayerMemory) upNext() (*Track, int) {
+	var remaining int
+
+	if m.shuffle {
+		for _, t := range m.tracks {
+			if _, ok := m.played[t.SrcUrlStr()]; !ok {
+				remaining++
+			}
+		}
+	} else if m.currentTrackIdx >= 0 {
+		remaining = len(m.tracks) - m.currentTrackIdx - 1
+	}
+
+	upcoming := m.upcomingTracks(1)
+	if len(upcoming) == 0 {
+		return nil, remaining
+	}
+
+	return &upcoming[0], remaining
+}
+
+// upNextMessage describes what plays after the current track
+func (p *Player) upNextMessage(current *Track) string {
+	var next *Track
+	var remaining int
+	var repeatMode RepeatMode
+
+	p.withMemory(func(m *PlayerMemory) {
+		next, remaining = m.upNext()
+		repeatMode = m.repeatMode
+	})
+
+	if next == nil {
+		return "up next: nothing, this is the last track"
+	}
+
+	if repeatMode == RepeatModeOne && next.SrcUrlStr() == current.SrcUrlStr() {
+		return "up next: this track again ( repeating current track )"
+	}
+
+	msg := "up next: " + next.Title()
+
+	switch remaining {
+	case 0:
+		msg += " ( the playlist repeats after this track )"
+	case 1:
+		msg += " ( last track in the playlist )"
+	default:
+		msg += fmt.Sprintf(" ( %d tracks left in the playlist )", remaining)
+	}
+
+	return msg
+}
+
 // hasAudience is broken in latest release of discord-go
 func (m *PlayerMemory) hasAudience(s *discordgo.Session, guildId string) bool {
 
@@ -274,6 +688,8 @@ type PlayCall struct {
 	AuthorID      string
 	AuthorMention string
 	AudioStreamer AudioStreamer
+	StartOffset   time.Duration
+	Placement     Placement
 }
 
 type Player struct {
@@ -283,6 +699,27 @@ type Player struct {
 	discordSession *discordgo.Session
 	discordGuildId string
 
+	transcodeManager *TranscodeManager
+	searcher         Searcher
+	library          *Library
+
+	// searchPicks are the recent search results of each author, keyed by author id
+	searchPicks SyncMap[string, searchPick]
+
+	// volume is read for every audio frame so changes apply mid-track
+	volume atomic.Int32
+
+	// playing is the track being played, nil when nothing is playing
+	playing atomic.Pointer[Track]
+
+	// playbackFrame is the number of audio frames of the playing track played so far
+	playbackFrame atomic.Int64
+
+	// controls is the guild's interactive now playing message, nil when there is none
+	controls atomic.Pointer[controlsMessage]
+	// changed signals the controls message needs to be refreshed
+	changed chan struct{}
+
 	stateMachine PlayerStateMachine
 	signalChan   chan TracedSignal
 	cancelMutex  sync.Mutex
@@ -290,16 +727,20 @@ type Player struct {
 	playPacks    chan (<-chan PlayCall)
 }
 
-func NewPlayer(ctx context.Context, wg *sync.WaitGroup, s *discordgo.Session, guildId string) *Player {
+func NewPlayer(ctx context.Context, wg *sync.WaitGroup, s *discordgo.Session, guildId string, tm *TranscodeManager, sr Searcher, lib *Library) *Player {
 
 	p := &Player{
-		wg:             wg,
-		discordSession: s,
-		discordGuildId: guildId,
-		signalChan:     make(chan TracedSignal, 1),
-		stateMachine:   newPlayerStateMachine(nil),
-		cancelFuncs:    map[*func(error)]struct{}{},
-		playPacks:      make(chan (<-chan PlayCall)),
+		wg:               wg,
+		discordSession:   s,
+		discordGuildId:   guildId,
+		transcodeManager: tm,
+		searcher:         sr,
+		library:          lib,
+		signalChan:       make(chan TracedSignal, 1),
+		stateMachine:     newPlayerStateMachine(nil),
+		cancelFuncs:      map[*func(error)]struct{}{},
+		playPacks:        make(chan (<-chan PlayCall)),
+		changed:          make(chan struct{}, 1),
 	}
 
 	p.memory.Store(PlayerMemory{
@@ -307,10 +748,14 @@ func NewPlayer(ctx context.Context, wg *sync.WaitGroup, s *discordgo.Session, gu
 		currentTrackIdx: -1,
 	})
 
+	p.volume.Store(int32(p.Settings().Volume))
+
 	wg.Add(1)
 	go p.playerGoroutine(ctx, wg)
 	wg.Add(1)
 	go p.playPackGoroutine(ctx, wg)
+	wg.Add(1)
+	go p.controlsGoroutine(ctx, wg)
 
 	return p
 }
@@ -349,6 +794,8 @@ func (p *Player) setState(s State) {
 	sm.state = s
 	now := time.Now()
 
+	p.notifyChanged()
+
 	if oldState == StateDefault {
 		return
 	}
@@ -391,14 +838,45 @@ func (p *Player) nextTrack() *Track {
 			return
 		}
 
-		m.currentTrackIdx++
+		trackEnded := m.trackEnded
+		m.trackEnded = false
 
-		if m.currentTrackIdx >= len(m.tracks) {
-			if m.notLooping {
-				m.currentTrackIdx = -1
+		if trackEnded && m.repeatMode == RepeatModeOne && !m.pinnedNext && m.currentTrackIdx >= 0 && m.currentTrackIdx < len(m.tracks) {
+			track := m.tracks[m.currentTrackIdx]
+			result = &track
+			return
+		}
+
+		if m.shuffle && !m.pinnedNext {
+			m.currentTrackIdx = m.nextShuffleTrackIdx()
+			if m.currentTrackIdx == -1 {
+				// every track has been heard
+				m.played = nil
 				return
 			}
-			m.currentTrackIdx = 0
+		} else {
+			m.currentTrackIdx++
+
+			if m.currentTrackIdx >= len(m.tracks) {
+				if m.repeatMode == RepeatModeOff {
+					m.currentTrackIdx = -1
+					m.pinnedNext = false
+					return
+				}
+				m.currentTrackIdx = 0
+			}
+		}
+
+		m.pinnedNext = false
+		m.recordPlayed()
+
+		if m.playNextUrl != "" && m.indexOfTrack(m.playNextUrl) <= m.currentTrackIdx {
+			// the last play next request has started playing or was removed
+			m.playNextUrl = ""
+		}
+
+		if m.shuffle {
+			m.planShuffle()
 		}
 
 		track := m.tracks[m.currentTrackIdx]
@@ -408,6 +886,143 @@ func (p *Player) nextTrack() *Track {
 	return result
 }
 
+// scheduleUpcomingTranscodes makes the current track the most important transcode job
+// of the guild and starts caching the next few tracks so they are ready before the
+// current one ends
+//
+// The number of tracks to look ahead is the guild's prefetch count.
+func (p *Player) scheduleUpcomingTranscodes(ctx context.Context, current *Track) {
+	n := p.Settings().PrefetchCount
+
+	var upcoming []Track
+	p.withMemory(func(m *PlayerMemory) {
+		upcoming = m.upcomingTracks(n)
+	})
+
+	priorities := map[string]TranscodePriority{
+		current.SrcUrlStr(): TranscodePriorityNowPlaying,
+	}
+	for i, t := range upcoming {
+		if _, ok := priorities[t.SrcUrlStr()]; ok {
+			continue
+		}
+
+		if i == 0 {
+			priorities[t.SrcUrlStr()] = TranscodePriorityNextUp
+		} else {
+			priorities[t.SrcUrlStr()] = TranscodePriorityPlaylist
+		}
+	}
+
+	p.transcodeManager.Reprioritize(p.discordGuildId, priorities)
+
+	for _, t := range upcoming {
+		p.cacheTrack(ctx, t, priorities[t.SrcUrlStr()])
+	}
+}
+
+// cacheTrack downloads and transcodes a track to the media cache in the background
+// unless it is already cached or scheduled to be cached
+func (p *Player) cacheTrack(ctx context.Context, t Track, priority TranscodePriority) {
+	if t.handle == nil || t.Live() || t.Cached() || !t.handle.cacheScheduled.CompareAndSwap(false, true) {
+		return
+	}
+
+	urlStr := t.SrcUrlStr()
+	ctx, cancel := t.bindContext(ctx)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		err := p.transcodeManager.Enqueue(ctx, TranscodeRequest{
+			GuildID:     p.discordGuildId,
+			Key:         urlStr,
+			Description: "cache " + urlStr,
+			Priority:    priority,
+			Run: func(ctx context.Context) {
+				defer cancel()
+
+				if err := ctx.Err(); err != nil {
+					t.handle.cacheScheduled.Store(false)
+					return
+				}
+
+				if err := t.DownloadAndTranscode(ctx); err != nil {
+					t.handle.cacheScheduled.Store(false)
+
+					if ctx.Err() != nil {
+						return
+					}
+
+					slog.ErrorContext(ctx,
+						"player: failed to cache upcoming track",
+						"error", err,
+						"guild_id", p.discordGuildId,
+						"url", urlStr,
+					)
+				}
+			},
+		})
+		if err != nil {
+			cancel()
+			t.handle.cacheScheduled.Store(false)
+		}
+	}()
+}
+
+func (p *Player) Settings() GuildSettings {
+	return loadGuildSettings(p.discordGuildId)
+}
+
+// SetPrefetchCount sets how many upcoming tracks are cached in the background while a track plays
+func (p *Player) SetPrefetchCount(n int) error {
+	_, err := updateGuildSettings(p.discordGuildId, func(gs *GuildSettings) {
+		gs.PrefetchCount = n
+	})
+
+	return err
+}
+
+// SetVolume sets the playback volume as a percentage, 100 being unaltered
+func (p *Player) SetVolume(v int) error {
+	gs, err := updateGuildSettings(p.discordGuildId, func(gs *GuildSettings) {
+		gs.Volume = v
+	})
+	if err != nil {
+		return err
+	}
+
+	p.volume.Store(int32(gs.Volume))
+
+	return nil
+}
+
+// SetAnnounceUpNext sets if track change broadcasts also say which track plays next
+func (p *Player) SetAnnounceUpNext(on bool) error {
+	_, err := updateGuildSettings(p.discordGuildId, func(gs *GuildSettings) {
+		gs.AnnounceUpNext = on
+	})
+
+	return err
+}
+
+func (p *Player) Volume() int {
+	return int(p.volume.Load())
+}
+
+func (p *Player) GuildID() string {
+	return p.discordGuildId
+}
+
+func (p *Player) TranscodeManager() *TranscodeManager {
+	return p.transcodeManager
+}
+
+func (p *Player) Library() *Library {
+	return p.library
+}
+
 func (p *Player) PlaylistID() PlaylistID {
 	var result PlaylistID
 
@@ -493,10 +1108,14 @@ func (p *Player) Reset(srcEvt interface{}) {
 }
 
 func (p *Player) reset() {
+	var oldTracks []Track
 	p.withMemory(func(m *PlayerMemory) {
+		oldTracks = m.tracks
 		m.reset()
 	})
 
+	cancelTracks(oldTracks)
+
 	// cancel all old async contexts for the previous playlist id
 	{
 		var oldMap map[*func(error)]struct{}
@@ -529,11 +1148,22 @@ func (p *Player) reset() {
 func (p *Player) restartTrack() {
 	p.withMemory(func(m *PlayerMemory) {
 		m.currentTrackIdx--
+		m.pinnedNext = true
 	})
 }
 
 func (p *Player) previousTrack(offset int) {
 	p.withMemory(func(m *PlayerMemory) {
+		// play next requests made after going back play right after the track going back to
+		m.playNextUrl = ""
+
+		// the history knows which track played before even after a jump, move, or play next
+		if m.previousFromHistory(offset > 0) {
+			return
+		}
+
+		m.pinnedNext = m.shuffle
+
 		if len(m.tracks) < 2 {
 			m.currentTrackIdx = -1
 			return
@@ -581,22 +1211,93 @@ func (p *Player) Previous(srcEvt interface{}) {
 	p.signalChan <- TracedSignal{srcEvt, SignalPrevious, nil}
 }
 
+// CycleRepeatMode moves to the next repeat mode in the order off, all, one
 func (p *Player) CycleRepeatMode() string {
 	var result string
 
 	p.withMemory(func(m *PlayerMemory) {
-		m.notLooping = !m.notLooping
-
-		if m.notLooping {
-			result = "not repeating playlist"
-		} else {
-			result = "repeating playlist"
+		switch m.repeatMode {
+		case RepeatModeOff:
+			m.repeatMode = RepeatModeAll
+		case RepeatModeAll:
+			m.repeatMode = RepeatModeOne
+		default:
+			m.repeatMode = RepeatModeOff
 		}
+
+		result = m.repeatMode.Description()
 	})
 
+	p.notifyChanged()
+
 	return result
 }
 
+func (p *Player) SetRepeatMode(r RepeatMode) string {
+	var result string
+
+	p.withMemory(func(m *PlayerMemory) {
+		m.repeatMode = r
+
+		result = m.repeatMode.Description()
+	})
+
+	p.notifyChanged()
+
+	return result
+}
+
+func (p *Player) RepeatMode() RepeatMode {
+	var result RepeatMode
+
+	p.withMemory(func(m *PlayerMemory) {
+		result = m.repeatMode
+	})
+
+	return result
+}
+
+// markTrackEnded records the current track played to its end
+func (p *Player) markTrackEnded() {
+	p.withMemory(func(m *PlayerMemory) {
+		m.trackEnded = true
+	})
+}
+
+type NowPlaying struct {
+	Track       Track
+	Position    time.Duration
+	Paused      bool
+	RepeatMode  RepeatMode
+	ShuffleMode string
+}
+
+// NowPlaying returns the track being played and how far along playback of it is
+//
+// Returns false when nothing is playing.
+func (p *Player) NowPlaying() (NowPlaying, bool) {
+	var result NowPlaying
+
+	t := p.playing.Load()
+	if t == nil {
+		return result, false
+	}
+
+	result.Track = *t
+	result.Position = BytesToDuration(p.playbackFrame.Load() * SampleMaxBytes)
+
+	p.stateMachine.rwMutex.RLock()
+	result.Paused = (p.stateMachine.state == StatePaused)
+	p.stateMachine.rwMutex.RUnlock()
+
+	p.withMemory(func(m *PlayerMemory) {
+		result.RepeatMode = m.repeatMode
+		result.ShuffleMode = m.shuffleModeDescription()
+	})
+
+	return result, true
+}
+
 func (p *Player) SetVoiceConnection(srcEvt interface{}, channelId string, c *discordgo.VoiceConnection) {
 
 	p.withMemory(func(m *PlayerMemory) {
@@ -623,6 +1324,104 @@ func (p *Player) RestartTrack(srcEvt interface{}) {
 	p.signalChan <- TracedSignal{srcEvt, SignalRestartTrack, nil}
 }
 
+// JumpToTrack starts playback of the playlist track with the given url
+func (p *Player) JumpToTrack(srcEvt interface{}, url string) {
+
+	p.signalChan <- TracedSignal{srcEvt, SignalJump, url}
+}
+
+// jumpToTrack makes the track named by a jump signal's payload play next
+//
+// Returns false if the track is no longer in the playlist.
+func (p *Player) jumpToTrack(v interface{}) bool {
+	url, ok := v.(string)
+	if !ok {
+		panic(errors.New("unreachable"))
+	}
+
+	var found bool
+	p.withMemory(func(m *PlayerMemory) {
+		i := m.indexOfTrack(url)
+		if i == -1 {
+			return
+		}
+
+		found = true
+		m.currentTrackIdx = i - 1
+		m.pinnedNext = true
+		m.playNextUrl = ""
+	})
+
+	if !found {
+		p.broadcastTextMessage("cannot jump: the track is no longer in the playlist")
+	}
+
+	return found
+}
+
+// Seek moves playback of the current track to pos from the start of the track
+func (p *Player) Seek(srcEvt interface{}, pos time.Duration) {
+
+	p.signalChan <- TracedSignal{srcEvt, SignalSeek, seekRequest{position: pos}}
+}
+
+// SeekRelative moves playback of the current track forward, or backward when delta is negative
+func (p *Player) SeekRelative(srcEvt interface{}, delta time.Duration) {
+
+	p.signalChan <- TracedSignal{srcEvt, SignalSeek, seekRequest{position: delta, relative: true}}
+}
+
+// seekTrack applies a seek signal's payload to the reader of the current track
+//
+// Returns true if the seek moved past the end of the track.
+func (p *Player) seekTrack(tr *trackReader, v interface{}) (bool, error) {
+	sr, ok := v.(seekRequest)
+	if !ok {
+		panic(errors.New("unreachable"))
+	}
+
+	if t := p.playing.Load(); t != nil && t.Live() {
+		p.broadcastTextMessage("cannot seek: the track is a live stream")
+		return false, nil
+	}
+
+	target := sr.position
+	if sr.relative {
+		target += tr.position()
+	}
+	if target < 0 {
+		target = 0
+	}
+
+	if err := tr.seek(target); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return true, nil
+		}
+
+		if errors.Is(err, ErrRewindWhileTranscoding) {
+			p.broadcastTextMessage("cannot rewind: the track is still being downloaded, try again once it has been cached")
+			return false, nil
+		}
+
+		if errors.Is(err, ErrSeekAheadOfTranscode) {
+			if sr.retry {
+				return false, err
+			}
+
+			p.broadcastTextMessage("cannot seek: the track has not been transcoded that far yet, try again shortly")
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	p.playbackFrame.Store(tr.frame())
+
+	p.broadcastTextMessage("playback position is now " + FormatDuration(tr.position()))
+
+	return false, nil
+}
+
 func (p *Player) SetTextChannel(s string) {
 	p.withMemory(func(m *PlayerMemory) {
 		m.textChannel = s
@@ -652,33 +1451,136 @@ func (p *Player) HasAudience() bool {
 }
 
 func (p *Player) RemoveTrack(url string) bool {
-	result := false
+	removed := p.RemoveTracks(func(_ int, t Track) bool {
+		return t.SrcUrlStr() == url
+	})
+
+	return len(removed) > 0
+}
+
+// RemoveTracks removes every track for which match returns true
+//
+// match is called once per track in playlist order with the track's index.
+// Returns the removed tracks.
+func (p *Player) RemoveTracks(match func(i int, t Track) bool) []Track {
+	var removed []Track
 
 	p.withMemory(func(m *PlayerMemory) {
 
-		i := m.indexOfTrack(url)
-		if i == -1 {
-			return
+		kept := make([]Track, 0, len(m.tracks))
+		currentTrackIdx := m.currentTrackIdx
+
+		for i, t := range m.tracks {
+			if !match(i, t) {
+				kept = append(kept, t)
+				continue
+			}
+
+			removed = append(removed, t)
+
+			if m.currentTrackIdx >= i {
+				currentTrackIdx--
+			}
 		}
 
-		result = true
+		if len(removed) == 0 {
+			return
+		}
 
-		if len(m.tracks) <= 1 {
+		if len(kept) == 0 {
 			m.tracks = nil
 			m.currentTrackIdx = -1
 
 			return
 		}
 
-		copy(m.tracks[i:], m.tracks[i+1:])
-		m.tracks = m.tracks[:len(m.tracks)-1]
+		m.tracks = kept
+		m.currentTrackIdx = currentTrackIdx
+	})
+
+	// stop any download, transcode, or playback of the removed tracks
+	cancelTracks(removed)
 
-		if m.currentTrackIdx >= i {
-			m.currentTrackIdx--
+	return removed
+}
+
+// keepingCurrentTrack runs f, which reorders the playlist, without changing which track is current
+func (m *PlayerMemory) keepingCurrentTrack(f func()) {
+	var anchor string
+	if m.pinnedNext {
+		if i := m.currentTrackIdx + 1; i >= 0 && i < len(m.tracks) {
+			anchor = m.tracks[i].SrcUrlStr()
 		}
+	} else if m.currentTrackIdx >= 0 && m.currentTrackIdx < len(m.tracks) {
+		anchor = m.tracks[m.currentTrackIdx].SrcUrlStr()
+	}
+
+	f()
+
+	if anchor == "" {
+		return
+	}
+
+	i := m.indexOfTrack(anchor)
+	if m.pinnedNext {
+		i--
+	}
+
+	m.currentTrackIdx = i
+}
+
+func (m *PlayerMemory) checkTrackIndex(i int) error {
+	if i < 0 || i >= len(m.tracks) {
+		return fmt.Errorf("no track at position %d: the playlist has %d tracks", i+1, len(m.tracks))
+	}
+
+	return nil
+}
+
+// MoveTrack moves the track at index from so it ends up at index to
+func (p *Player) MoveTrack(from, to int) error {
+	return p.withMemoryErr(func(m *PlayerMemory) error {
+		if err := m.checkTrackIndex(from); err != nil {
+			return err
+		}
+
+		if err := m.checkTrackIndex(to); err != nil {
+			return err
+		}
+
+		m.keepingCurrentTrack(func() {
+			t := m.tracks[from]
+
+			if from < to {
+				copy(m.tracks[from:to], m.tracks[from+1:to+1])
+			} else {
+				copy(m.tracks[to+1:from+1], m.tracks[to:from])
+			}
+
+			m.tracks[to] = t
+		})
+
+		return nil
 	})
+}
 
-	return result
+// SwapTracks exchanges the positions of the tracks at indexes a and b
+func (p *Player) SwapTracks(a, b int) error {
+	return p.withMemoryErr(func(m *PlayerMemory) error {
+		if err := m.checkTrackIndex(a); err != nil {
+			return err
+		}
+
+		if err := m.checkTrackIndex(b); err != nil {
+			return err
+		}
+
+		m.keepingCurrentTrack(func() {
+			m.tracks[a], m.tracks[b] = m.tracks[b], m.tracks[a]
+		})
+
+		return nil
+	})
 }
 
 func (p *Player) GetPlaylist() Playlist {
@@ -694,6 +1596,7 @@ func (p *Player) GetPlaylist() Playlist {
 		copy(result.Tracks, m.tracks)
 
 		result.CurrentTrackIdx = m.currentTrackIdx
+		result.RepeatMode = m.repeatMode
 	})
 
 	if len(result.Tracks) == 0 {
@@ -836,18 +1739,28 @@ func (p *Player) playPackGoroutine(ctx context.Context, wg *sync.WaitGroup) {
 				continue
 			}
 
+			// shared by all tracks of the play pack so inserted tracks keep their order
+			var cursor *insertCursor
+
 			for v := range playPackChan {
 				as := v.AudioStreamer
 
+				if cursor == nil && !v.Placement.isAppend() {
+					cursor = &insertCursor{placement: v.Placement}
+				}
+
 				// TODO: pool
 				payload := &playRequest{
 					track: &Track{
 						AudioStreamer: as,
 						AuthorId:      v.AuthorID,
 						AuthorMention: v.AuthorMention,
+						StartOffset:   v.StartOffset,
+						handle:        newTrackHandle(),
 					},
 					playlistID: as.PlaylistID(),
 					pslc:       as.PlayerStateLastChangedAt(),
+					cursor:     cursor,
 				}
 
 				var ctxExpired bool
@@ -1075,6 +1988,12 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 			// else stay in the idle state
 		case SignalResume:
 			p.setState(StatePlaying)
+		case SignalSeek:
+			p.broadcastTextMessage("cannot seek: nothing is playing")
+		case SignalJump:
+			if p.jumpToTrack(s.signalPayload) {
+				p.setState(StatePlaying)
+			}
 		case SignalNext:
 			// do nothing, let loop normally advance
 		case SignalPrevious:
@@ -1116,37 +2035,67 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 	}
 
 	msg := "now playing: " + track.SrcUrlStr()
+	if title := track.Metadata().Title; title != "" {
+		msg = "now playing: " + title + " ( " + track.SrcUrlStr() + " )"
+	}
 	if track.AuthorMention != "" {
 		msg += " ( added by " + track.AuthorMention + " )"
 	}
 
+	if p.Settings().AnnounceUpNext {
+		msg += "\n" + p.upNextMessage(track)
+	}
+
 	p.broadcastTextMessage(msg)
 
+	p.scheduleUpcomingTranscodes(ctx, track)
+
 	pctx := ctx
 	if pctx.Err() != nil {
 		return ErrDisposed
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
+	ctx, cancel := track.bindContext(ctx)
 	defer cancel()
 
 	f, err := track.ReadCloser(ctx, p.wg)
 	if err != nil {
 		return fmt.Errorf("failed to open audio stream: %s, %t: %w", track.SrcUrlStr(), track.Cached(), err)
 	}
-	defer f.Close()
+	defer func() {
+		// f is replaced when a paused live track resumes
+		f.Close()
+	}()
 
 	//
 	// read packets from file and buffer them to send to broadcast channel
 	//
 
-	br := bufio.NewReaderSize(f, SampleMaxBytes)
+	tr := newTrackReader(f)
+
+	p.playbackFrame.Store(0)
+	p.playing.Store(track)
+	p.notifyChanged()
+	defer func() {
+		p.playing.Store(nil)
+		p.notifyChanged()
+	}()
+
+	// applied in the playback loop so signals are still handled while a just in
+	// time transcode catches up with the start position
+	startPosition := track.startPosition()
 
 	opusEncoder, err := gopus.NewEncoder(SampleRate, NumChannels, gopus.Audio)
 	if err != nil {
 		return err
 	}
 
+	// normalize loudness across tracks when the track has been analyzed
+	trackGain := 1.0
+	if l, ok := track.Loudness(); ok {
+		trackGain = l.Gain()
+	}
+
 	pcmBuf := [SampleSize]int16{}
 
 	for {
@@ -1195,6 +2144,9 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 					// ignore signal, don't change any state, continue playback
 					continue
 				}
+
+				// the new track may be within the prefetch window
+				p.scheduleUpcomingTranscodes(pctx, track)
 			case SignalPrevious:
 				p.previousTrack(1) // current track is playing
 				return nil
@@ -1211,9 +2163,27 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 			case SignalRestartTrack:
 				p.restartTrack()
 				return nil
+			case SignalJump:
+				if p.jumpToTrack(s.signalPayload) {
+					return nil
+				}
+			case SignalSeek:
+				startPosition = 0
+
+				if ended, err := p.seekTrack(tr, s.signalPayload); err != nil || ended {
+					if ended {
+						p.markTrackEnded()
+					}
+					return err
+				}
 			case SignalPause:
 				p.setState(StatePaused)
 
+				// a live stream cannot wait while paused, it is disconnected and reconnected on resume
+				if track.Live() {
+					f.Close()
+				}
+
 			PausedLoop:
 				for {
 					// signal trap 4/4:
@@ -1253,6 +2223,9 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 							continue
 						}
 
+						// the new track may be within the prefetch window
+						p.scheduleUpcomingTranscodes(pctx, track)
+
 						if isSyncCall {
 							broadcastMsg := "player is paused; to resume playback send the following message:\n\n" +
 								p.discordSession.State.User.Mention() + " resume"
@@ -1274,6 +2247,21 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 						p.restartTrack()
 						p.setState(StateIdle)
 						return nil
+					case SignalJump:
+						if p.jumpToTrack(s.signalPayload) {
+							p.setState(StatePlaying)
+							return nil
+						}
+					case SignalSeek:
+						startPosition = 0
+
+						if ended, err := p.seekTrack(tr, s.signalPayload); err != nil || ended {
+							if ended {
+								p.markTrackEnded()
+							}
+							p.setState(StateIdle)
+							return err
+						}
 					case SignalReset:
 						p.reset()
 						p.setState(StateIdle)
@@ -1284,6 +2272,28 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 					}
 				}
 
+				if track.Live() {
+					f, err = track.ReadCloser(ctx, p.wg)
+					if err != nil {
+						// keep the deferred close from closing the old stream again
+						f = io.NopCloser(nil)
+
+						p.broadcastTextMessage("failed to reconnect to live stream: " + track.Title())
+						slog.ErrorContext(ctx,
+							"player: failed to reconnect to live stream",
+							"error", err,
+							"guild_id", p.discordGuildId,
+							"url", track.SrcUrlStr(),
+						)
+						return nil
+					}
+
+					// the position keeps counting how long the stream has been listened to
+					pos := tr.pos
+					tr = newTrackReader(f)
+					tr.pos = pos
+				}
+
 				// rediscover the channel we need to send on
 				// if it was altered while paused
 				if sendChan == nil {
@@ -1308,10 +2318,54 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 			return ErrDisposed
 		}
 
-		err = binary.Read(br, binary.LittleEndian, &pcmBuf)
+		if errors.Is(context.Cause(ctx), ErrTrackRemoved) {
+			p.debug("track removed while playing")
+			return nil
+		}
+
+		if startPosition > 0 {
+			ended, err := p.seekTrack(tr, seekRequest{position: startPosition, retry: true})
+			if errors.Is(err, ErrSeekAheadOfTranscode) {
+				time.Sleep(transcodingFilePollInterval)
+				continue
+			}
+
+			startPosition = 0
+
+			if err != nil || ended {
+				if ended {
+					p.markTrackEnded()
+				}
+				return err
+			}
+		}
+
+		err = tr.readFrame(&pcmBuf)
 		if err != nil {
+			if errors.Is(context.Cause(ctx), ErrTrackRemoved) {
+				p.debug("track removed while playing")
+				return nil
+			}
+
+			// live streams reconnect on their own, so any end means the stream was lost
+			//
+			// Losing a live stream moves on to the next track rather than counting
+			// as a playback error, because enough errors reset the player.
+			if track.Live() {
+				p.broadcastTextMessage("live stream lost: " + track.Title())
+				slog.ErrorContext(ctx,
+					"player: live stream lost",
+					"error", err,
+					"guild_id", p.discordGuildId,
+					"url", track.SrcUrlStr(),
+				)
+				return nil
+			}
+
 			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 
+				p.markTrackEnded()
+
 				if flushable, ok := f.(interface{ Flushed() bool }); ok {
 					p.debug("flushing track")
 					if flushable.Flushed() {
@@ -1331,6 +2385,10 @@ func (p *Player) playerStateMachine(ctx context.Context) error {
 			return fmt.Errorf("error reading track: %s: %w", track.SrcUrlStr(), err)
 		}
 
+		p.playbackFrame.Store(tr.frame())
+
+		applyGain(pcmBuf[:], trackGain*float64(p.volume.Load())/100)
+
 		numBytes, err := opusEncoder.Encode(pcmBuf[:], SampleSize, packet)
 		if numBytes == 0 {
 			if err == nil {
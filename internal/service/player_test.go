@@ -0,0 +1,299 @@
+package service_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// playlistCase describes a playlist change made while the track at currentTrackIdx is current
+type playlistCase struct {
+	name            string
+	currentTrackIdx int
+	pinnedNext      bool
+	a, b            int
+	tracks          []string
+	wantIdx         int
+}
+
+func TestPlayerReorder(t *testing.T) {
+	Convey("moving a track keeps the current track and the track pinned to play next", t, func() {
+		cases := []playlistCase{
+			{"current track to the start", 2, false, 2, 0, []string{"c", "a", "b", "d", "e"}, 0},
+			{"current track to the end", 2, false, 2, 4, []string{"a", "b", "d", "e", "c"}, 4},
+			{"earlier track past the current track", 2, false, 0, 4, []string{"b", "c", "d", "e", "a"}, 1},
+			{"later track before the current track", 2, false, 4, 0, []string{"e", "a", "b", "c", "d"}, 3},
+			{"track just before the current track to just after it", 2, false, 1, 3, []string{"a", "c", "d", "b", "e"}, 1},
+			{"track just after the current track to just before it", 2, false, 3, 1, []string{"a", "d", "b", "c", "e"}, 3},
+			{"track after the current track stays after it", 2, false, 3, 4, []string{"a", "b", "c", "e", "d"}, 2},
+			{"pinned track to the start", 1, true, 2, 0, []string{"c", "a", "b", "d", "e"}, -1},
+			{"earlier track past the pinned track", 1, true, 0, 4, []string{"b", "c", "d", "e", "a"}, 0},
+			{"track before the pinned track to just after it", 1, true, 1, 3, []string{"a", "c", "d", "b", "e"}, 0},
+			{"nothing playing", -1, false, 0, 4, []string{"b", "c", "d", "e", "a"}, -1},
+		}
+
+		for _, c := range cases {
+			Convey(c.name, func() {
+				p := service.NewPlaylistTestPlayer("a", "b", "c", "d", "e")
+				p.SetTestPosition(c.currentTrackIdx, c.pinnedNext)
+
+				So(p.MoveTrack(c.a, c.b), ShouldBeNil)
+
+				tracks, currentTrackIdx, pinnedNext := p.TestPosition()
+				So(tracks, ShouldResemble, c.tracks)
+				So(currentTrackIdx, ShouldEqual, c.wantIdx)
+				So(pinnedNext, ShouldEqual, c.pinnedNext)
+			})
+		}
+	})
+
+	Convey("swapping tracks keeps the current track and the track pinned to play next", t, func() {
+		cases := []playlistCase{
+			{"current track with the first track", 2, false, 2, 0, []string{"c", "b", "a", "d", "e"}, 0},
+			{"current track with the last track", 2, false, 4, 2, []string{"a", "b", "e", "d", "c"}, 4},
+			{"tracks either side of the current track", 2, false, 1, 3, []string{"a", "d", "c", "b", "e"}, 2},
+			{"current track with the track after it", 2, false, 2, 3, []string{"a", "b", "d", "c", "e"}, 3},
+			{"tracks after the current track", 2, false, 3, 4, []string{"a", "b", "c", "e", "d"}, 2},
+			{"pinned track with the last track", 1, true, 2, 4, []string{"a", "b", "e", "d", "c"}, 3},
+			{"tracks either side of the pinned track", 1, true, 1, 3, []string{"a", "d", "c", "b", "e"}, 1},
+			{"nothing playing", -1, false, 0, 4, []string{"e", "b", "c", "d", "a"}, -1},
+		}
+
+		for _, c := range cases {
+			Convey(c.name, func() {
+				p := service.NewPlaylistTestPlayer("a", "b", "c", "d", "e")
+				p.SetTestPosition(c.currentTrackIdx, c.pinnedNext)
+
+				So(p.SwapTracks(c.a, c.b), ShouldBeNil)
+
+				tracks, currentTrackIdx, pinnedNext := p.TestPosition()
+				So(tracks, ShouldResemble, c.tracks)
+				So(currentTrackIdx, ShouldEqual, c.wantIdx)
+				So(pinnedNext, ShouldEqual, c.pinnedNext)
+			})
+		}
+	})
+
+	Convey("positions outside the playlist are rejected", t, func() {
+		p := service.NewPlaylistTestPlayer("a", "b", "c")
+		p.SetTestPosition(1, false)
+
+		So(p.MoveTrack(0, 3), ShouldNotBeNil)
+		So(p.SwapTracks(-1, 2), ShouldNotBeNil)
+
+		tracks, currentTrackIdx, _ := p.TestPosition()
+		So(tracks, ShouldResemble, []string{"a", "b", "c"})
+		So(currentTrackIdx, ShouldEqual, 1)
+	})
+}
+
+func TestPlayerInsert(t *testing.T) {
+	Convey("inserted tracks keep the current track", t, func() {
+		cases := []struct {
+			name      string
+			placement service.Placement
+			urls      []string
+			tracks    []string
+			wantIdx   int
+		}{
+			{"at the start", service.Placement{Position: 1}, []string{"x"}, []string{"x", "a", "b", "c"}, 2},
+			{"just before the current track", service.Placement{Position: 2}, []string{"x"}, []string{"a", "x", "b", "c"}, 2},
+			{"at the end", service.Placement{Position: 4}, []string{"x"}, []string{"a", "b", "c", "x"}, 1},
+			{"past the end", service.Placement{Position: 10}, []string{"x"}, []string{"a", "b", "c", "x"}, 1},
+			{"a playlist before the current track", service.Placement{Position: 1}, []string{"x", "y"}, []string{"x", "y", "a", "b", "c"}, 3},
+			{"a playlist to play next", service.Placement{Next: true}, []string{"x", "y"}, []string{"a", "b", "x", "y", "c"}, 1},
+		}
+
+		for _, c := range cases {
+			Convey(c.name, func() {
+				p := service.NewPlaylistTestPlayer("a", "b", "c")
+				p.SetTestPosition(1, false)
+
+				p.PlayTestTracks(service.StatePlaying, c.placement, c.urls...)
+
+				tracks, currentTrackIdx, _ := p.TestPosition()
+				So(tracks, ShouldResemble, c.tracks)
+				So(currentTrackIdx, ShouldEqual, c.wantIdx)
+			})
+		}
+	})
+
+	Convey("play next requests play in the order they were made", t, func() {
+		p := service.NewPlaylistTestPlayer("a", "b", "c")
+		p.SetTestPosition(1, false)
+
+		p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "x", "y")
+		p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "z")
+
+		tracks, currentTrackIdx, _ := p.TestPosition()
+		So(tracks, ShouldResemble, []string{"a", "b", "x", "y", "z", "c"})
+		So(currentTrackIdx, ShouldEqual, 1)
+
+		Convey("until the earlier request has played", func() {
+			p.SetTestPosition(4, false)
+
+			p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "w")
+
+			tracks, currentTrackIdx, _ := p.TestPosition()
+			So(tracks, ShouldResemble, []string{"a", "b", "x", "y", "z", "w", "c"})
+			So(currentTrackIdx, ShouldEqual, 4)
+		})
+	})
+
+	Convey("play next goes right after the current track once playback has gone back before an earlier request", t, func() {
+		p := service.NewPlaylistTestPlayer("a", "b", "c", "d", "e")
+		p.SetTestPosition(3, false)
+
+		p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "x")
+
+		p.PreviousTestTrack()
+		So(p.NextTestTrack(), ShouldEqual, "c")
+
+		p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "y")
+
+		tracks, currentTrackIdx, _ := p.TestPosition()
+		So(tracks, ShouldResemble, []string{"a", "b", "c", "y", "d", "x", "e"})
+		So(currentTrackIdx, ShouldEqual, 2)
+
+		Convey("and after jumping to another track", func() {
+			So(p.JumpTestTrack("a"), ShouldBeTrue)
+			So(p.NextTestTrack(), ShouldEqual, "a")
+
+			p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "z")
+
+			tracks, currentTrackIdx, _ := p.TestPosition()
+			So(tracks, ShouldResemble, []string{"a", "z", "b", "c", "y", "d", "x", "e"})
+			So(currentTrackIdx, ShouldEqual, 0)
+		})
+	})
+
+	Convey("play next requests play next and in order while shuffling", t, func() {
+		for range 20 {
+			p := service.NewPlaylistTestPlayer("a", "b", "c", "d", "e")
+			p.SetShuffleMode(true)
+			p.NextTestTrack()
+
+			p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "x", "y")
+			p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "z")
+
+			So(p.NextTestTrack(), ShouldEqual, "x")
+			So(p.NextTestTrack(), ShouldEqual, "y")
+			So(p.NextTestTrack(), ShouldEqual, "z")
+			So(p.NextTestTrack(), ShouldNotBeIn, []string{"x", "y", "z"})
+		}
+	})
+
+	Convey("play next goes after a track that is about to replay", t, func() {
+		p := service.NewPlaylistTestPlayer("a", "b", "c")
+		p.SetTestPosition(0, true)
+
+		p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "x")
+
+		tracks, currentTrackIdx, pinnedNext := p.TestPosition()
+		So(tracks, ShouldResemble, []string{"a", "b", "x", "c"})
+		So(currentTrackIdx, ShouldEqual, 0)
+		So(pinnedNext, ShouldBeTrue)
+	})
+}
+
+func TestPlayerRemove(t *testing.T) {
+	Convey("removing tracks keeps the current track and the track pinned to play next", t, func() {
+		cases := []struct {
+			name            string
+			currentTrackIdx int
+			pinnedNext      bool
+			remove          []string
+			tracks          []string
+			wantIdx         int
+		}{
+			{"before the current track", 2, false, []string{"b"}, []string{"a", "c", "d", "e"}, 1},
+			{"the current track", 2, false, []string{"c"}, []string{"a", "b", "d", "e"}, 1},
+			{"after the current track", 2, false, []string{"d"}, []string{"a", "b", "c", "e"}, 2},
+			{"either side of the current track", 2, false, []string{"a", "e"}, []string{"b", "c", "d"}, 1},
+			{"the first track while it is current", 0, false, []string{"a"}, []string{"b", "c", "d", "e"}, -1},
+			{"before the pinned track", 1, true, []string{"a"}, []string{"b", "c", "d", "e"}, 0},
+			{"just before the pinned track", 1, true, []string{"b"}, []string{"a", "c", "d", "e"}, 0},
+			{"the pinned track", 1, true, []string{"c"}, []string{"a", "b", "d", "e"}, 1},
+			{"after the pinned track", 1, true, []string{"d"}, []string{"a", "b", "c", "e"}, 1},
+			{"nothing playing", -1, false, []string{"a"}, []string{"b", "c", "d", "e"}, -1},
+			{"every track", 2, false, []string{"a", "b", "c", "d", "e"}, nil, -1},
+		}
+
+		for _, c := range cases {
+			Convey(c.name, func() {
+				p := service.NewPlaylistTestPlayer("a", "b", "c", "d", "e")
+				p.SetTestPosition(c.currentTrackIdx, c.pinnedNext)
+
+				removed := p.RemoveTracks(func(_ int, t service.Track) bool {
+					return slices.Contains(c.remove, t.SrcUrlStr())
+				})
+				So(len(removed), ShouldEqual, len(c.remove))
+
+				tracks, currentTrackIdx, _ := p.TestPosition()
+				So(tracks, ShouldResemble, c.tracks)
+				So(currentTrackIdx, ShouldEqual, c.wantIdx)
+			})
+		}
+	})
+
+	Convey("work bound to a removed track is canceled", t, func() {
+		p := service.NewPlaylistTestPlayer("a", "b", "c")
+		tracks := p.GetPlaylist().Tracks
+
+		removedCtx, cancelRemoved := service.BindTestTrack(context.Background(), tracks[1])
+		defer cancelRemoved()
+
+		keptCtx, cancelKept := service.BindTestTrack(context.Background(), tracks[2])
+		defer cancelKept()
+
+		So(p.RemoveTrack("b"), ShouldBeTrue)
+		So(p.RemoveTrack("b"), ShouldBeFalse)
+
+		So(removedCtx.Err(), ShouldEqual, context.Canceled)
+		So(context.Cause(removedCtx), ShouldEqual, service.ErrTrackRemoved)
+		So(keptCtx.Err(), ShouldBeNil)
+
+		// work started after the track was removed never runs
+		ctx, cancel := service.BindTestTrack(context.Background(), tracks[1])
+		defer cancel()
+		So(context.Cause(ctx), ShouldEqual, service.ErrTrackRemoved)
+	})
+}
+
+func TestPlayerPrevious(t *testing.T) {
+	Convey("previous goes back to the track that played before the current one", t, func() {
+		p := service.NewPlaylistTestPlayer("a", "b", "c", "d", "e", "f", "g")
+		So(p.NextTestTrack(), ShouldEqual, "a")
+		So(p.NextTestTrack(), ShouldEqual, "b")
+
+		Convey("after a jump", func() {
+			So(p.JumpTestTrack("f"), ShouldBeTrue)
+			So(p.NextTestTrack(), ShouldEqual, "f")
+
+			p.PreviousTestTrack()
+			So(p.NextTestTrack(), ShouldEqual, "b")
+
+			p.PreviousTestTrack()
+			So(p.NextTestTrack(), ShouldEqual, "a")
+		})
+
+		Convey("after a play next request", func() {
+			p.PlayTestTracks(service.StatePlaying, service.Placement{Next: true}, "x")
+			So(p.NextTestTrack(), ShouldEqual, "x")
+
+			p.PreviousTestTrack()
+			So(p.NextTestTrack(), ShouldEqual, "b")
+		})
+	})
+
+	Convey("previous goes to the track before the current one when nothing has played", t, func() {
+		p := service.NewPlaylistTestPlayer("a", "b", "c")
+		p.SetTestPosition(2, false)
+
+		p.PreviousTestTrack()
+		So(p.NextTestTrack(), ShouldEqual, "b")
+	})
+}
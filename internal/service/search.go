@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	SearchResultLimit = 5
+	// SearchPickTTL is how long the results of a search can be picked from
+	SearchPickTTL = 10 * time.Minute
+)
+
+var (
+	ErrNoSearchResults    = errors.New("no search results")
+	ErrNoSearchToPickFrom = errors.New("no recent search to pick from")
+)
+
+// SearchResult is a playable track found by a Searcher
+type SearchResult struct {
+	URL      string
+	Title    string
+	Channel  string
+	Duration time.Duration
+}
+
+// Searcher finds tracks matching free text
+//
+// Results are returned in the order the backend ranks them, best first.
+type Searcher interface {
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+// IsSearchQuery reports if text should be searched for rather than treated as a url
+func IsSearchQuery(text string) bool {
+	if strings.ContainsAny(text, " \t\r\n") {
+		return true
+	}
+
+	u, err := url.Parse(text)
+	if err != nil || u == nil {
+		return true
+	}
+
+	return (u.Scheme != "http" && u.Scheme != "https") || u.Host == ""
+}
+
+// BestSearchResult searches for query and returns the result that best matches it
+//
+// Results whose title and channel contain more of the query's words win; ties go
+// to the result the backend ranked higher.
+func BestSearchResult(ctx context.Context, sr Searcher, query string) (SearchResult, error) {
+	var result SearchResult
+
+	results, err := sr.Search(ctx, query, SearchResultLimit)
+	if err != nil {
+		return result, err
+	}
+
+	if len(results) == 0 {
+		return result, ErrNoSearchResults
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+
+	bestScore := -1
+	for _, r := range results {
+		text := strings.ToLower(r.Title + " " + r.Channel)
+
+		var score int
+		for _, w := range words {
+			if strings.Contains(text, w) {
+				score++
+			}
+		}
+
+		if score > bestScore {
+			bestScore = score
+			result = r
+		}
+	}
+
+	return result, nil
+}
+
+type searchPick struct {
+	results   []SearchResult
+	expiresAt time.Time
+}
+
+// SetSearchResults remembers the results of a search so the author can pick one of them later
+func (p *Player) SetSearchResults(authorID string, results []SearchResult) {
+	p.searchPicks.Store(authorID, searchPick{
+		results:   results,
+		expiresAt: time.Now().Add(SearchPickTTL),
+	})
+}
+
+// SearchResult returns the n-th (1-based) result of the author's most recent search
+func (p *Player) SearchResult(authorID string, n int) (SearchResult, error) {
+	var result SearchResult
+
+	v, ok := p.searchPicks.Load(authorID)
+	if !ok || time.Now().After(v.expiresAt) {
+		p.searchPicks.Delete(authorID)
+		return result, ErrNoSearchToPickFrom
+	}
+
+	if n < 1 || n > len(v.results) {
+		return result, fmt.Errorf("pick must be between 1 and %d", len(v.results))
+	}
+
+	return v.results[n-1], nil
+}
+
+func (p *Player) Searcher() Searcher {
+	return p.searcher
+}
@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type fakeSearcher struct {
+	results []service.SearchResult
+	queries []string
+}
+
+func (fs *fakeSearcher) Search(_ context.Context, query string, limit int) ([]service.SearchResult, error) {
+	fs.queries = append(fs.queries, query)
+
+	if len(fs.results) > limit {
+		return fs.results[:limit], nil
+	}
+
+	return fs.results, nil
+}
+
+func TestSearch(t *testing.T) {
+	Convey("only text that is not an http url is searched for", t, func() {
+		So(service.IsSearchQuery("https://www.youtube.com/watch?v=dQw4w9WgXcQ"), ShouldBeFalse)
+		So(service.IsSearchQuery("http://example.com/song.mp3"), ShouldBeFalse)
+		So(service.IsSearchQuery("never gonna give you up"), ShouldBeTrue)
+		So(service.IsSearchQuery("rickroll"), ShouldBeTrue)
+		So(service.IsSearchQuery("www.youtube.com/watch?v=dQw4w9WgXcQ"), ShouldBeTrue)
+	})
+
+	Convey("the result matching the most query words wins, ties go to the higher ranked result", t, func() {
+		sr := &fakeSearcher{
+			results: []service.SearchResult{
+				{URL: "https://www.youtube.com/watch?v=1", Title: "Give Up (Reaction)", Channel: "Someone"},
+				{URL: "https://www.youtube.com/watch?v=2", Title: "Never Gonna Give You Up", Channel: "Rick Astley"},
+				{URL: "https://www.youtube.com/watch?v=3", Title: "Never Gonna Give You Up (Live)", Channel: "Rick Astley"},
+			},
+		}
+
+		r, err := service.BestSearchResult(context.Background(), sr, "Rick Astley give you up")
+		So(err, ShouldBeNil)
+		So(r.URL, ShouldEqual, "https://www.youtube.com/watch?v=2")
+		So(sr.queries, ShouldResemble, []string{"Rick Astley give you up"})
+	})
+
+	Convey("a search without results is an error", t, func() {
+		_, err := service.BestSearchResult(context.Background(), &fakeSearcher{}, "nothing at all")
+		So(err, ShouldEqual, service.ErrNoSearchResults)
+	})
+
+	Convey("youtube search results are found in the same order every time", t, func() {
+		video := func(id, title string) string {
+			return fmt.Sprintf(`{"videoRenderer":{"videoId":%q,"title":{"runs":[{"text":%q}]},"ownerText":{"runs":[{"text":"Channel"}]},"lengthText":{"simpleText":"3:15"}}}`, id, title)
+		}
+
+		// renderers in two sibling branches of the page data
+		page := `<script>var ytInitialData = {"contents":{` +
+			`"secondaryContents":{"contents":[` + video("3", "Third") + `,` + video("4", "Fourth") + `]},` +
+			`"primaryContents":{"contents":[` + video("1", "First") + `,` + video("2", "Second") + `]}` +
+			`}};</script>`
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, page)
+		}))
+		defer srv.Close()
+
+		ys := service.NewYoutubeSearcher(service.YoutubeSearchURLOption(srv.URL))
+
+		for range 20 {
+			results, err := ys.Search(context.Background(), "anything", 3)
+			So(err, ShouldBeNil)
+
+			titles := make([]string, 0, len(results))
+			for _, r := range results {
+				titles = append(titles, r.Title)
+			}
+			So(titles, ShouldResemble, []string{"First", "Second", "Third"})
+		}
+	})
+}
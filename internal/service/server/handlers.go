@@ -20,14 +20,30 @@ func (s *Server) Handlers(ctx context.Context) error {
 
 	s.addMuxHandlers(ctx)
 
+	s.addInteractionHandlers(ctx)
+
 	s.AddHandler(handlers.Ping())
 
 	s.AddHandler(handlers.JoinChannel())
 
 	s.AddHandler(handlers.Reset())
 
+	s.AddHandler(handlers.PlayNext()) // must come before play
+
 	s.AddHandler(handlers.Play())
 
+	s.AddHandler(handlers.Insert())
+
+	s.AddHandler(handlers.Search())
+
+	s.AddHandler(handlers.SearchPlayButton())
+
+	s.AddHandler(handlers.Pick())
+
+	s.AddHandler(handlers.LibrarySearch())
+
+	s.AddHandler(handlers.LibraryRescan())
+
 	s.AddHandler(handlers.Resume()) // also alias for play ( without args )
 
 	s.AddHandler(handlers.Pause())
@@ -36,12 +52,22 @@ func (s *Server) Handlers(ctx context.Context) error {
 
 	s.AddHandler(handlers.Repeat())
 
+	s.AddHandler(handlers.Shuffle())
+
 	s.AddHandler(handlers.Next()) // also alias for skip
 
+	s.AddHandler(handlers.Jump()) // also alias for skip to
+
 	s.AddHandler(handlers.Previous()) // also alias for prev
 
 	s.AddHandler(handlers.RestartTrack())
 
+	s.AddHandler(handlers.Seek())
+
+	s.AddHandler(handlers.Forward())
+
+	s.AddHandler(handlers.Rewind())
+
 	s.AddHandler(handlers.ClearPlaylist())
 
 	s.AddHandler(handlers.Echo())
@@ -50,12 +76,32 @@ func (s *Server) Handlers(ctx context.Context) error {
 
 	s.AddHandler(handlers.ShowPlaylist())
 
+	s.AddHandler(handlers.ShowPlaylistPage())
+
+	s.AddHandler(handlers.NowPlaying())
+
+	s.AddHandler(handlers.Controls())
+
+	s.AddHandler(handlers.ControlsButton())
+
 	s.AddHandler(handlers.RemoveTrack())
 
+	s.AddHandler(handlers.Move())
+
+	s.AddHandler(handlers.Swap())
+
 	s.AddHandler(handlers.ClearCache())
 
 	s.AddHandler(handlers.Cache())
 
+	s.AddHandler(handlers.TranscodeStatus())
+
+	s.AddHandler(handlers.Prefetch())
+
+	s.AddHandler(handlers.Volume())
+
+	s.AddHandler(handlers.UpNext())
+
 	s.DiscordSession.AddHandler(func(session *discordgo.Session, evt *discordgo.VoiceStateUpdate) {
 		// https://discord.com/developers/docs/topics/gateway#voice-state-update
 		// Sent when someone joins/leaves/moves voice channels. Inner payload is a voice state object.
@@ -167,6 +213,8 @@ func (s *Server) Handlers(ctx context.Context) error {
 	// always keep last, it analyzes registered handlers
 	s.AddHandler(handlers.Help(s.EventHandlers.MessageCreate))
 
+	s.indexSlashCommands()
+
 	return nil
 }
 
@@ -452,6 +500,9 @@ func (s *Server) AddHandler(v interface{}) {
 	case handlers.HandleMessageCreate:
 		s.EventHandlers.MessageCreate = append(s.EventHandlers.MessageCreate, h)
 
+	case handlers.HandleMessageComponent:
+		s.EventHandlers.MessageComponent = append(s.EventHandlers.MessageComponent, h)
+
 	default:
 		const msg = "code-error: failed to register handler"
 		slog.Error(
@@ -2,7 +2,9 @@ package server_test
 
 import (
 	"context"
+	"regexp"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/josephcopenhaver/melody-bot/internal/service/server"
 	"github.com/josephcopenhaver/melody-bot/internal/service/testing/testconfig"
@@ -22,3 +24,43 @@ func TestNormalHandlerRegistration(t *testing.T) {
 		So(err, ShouldEqual, nil)
 	})
 }
+
+func TestSlashCommandDerivation(t *testing.T) {
+	Convey("every message handler should derive a slash command discord accepts", t, func() {
+		conf, err := testconfig.New()
+		So(err, ShouldBeNil)
+
+		s := server.New()
+		So(s.SetConfig(conf), ShouldBeNil)
+		So(s.Handlers(context.Background()), ShouldBeNil)
+
+		nameRegexp := regexp.MustCompile(`^[-_a-z0-9]{1,32}$`)
+
+		names := map[string]struct{}{}
+		for i := range s.EventHandlers.MessageCreate {
+			cmd := s.EventHandlers.MessageCreate[i].SlashCommand()
+
+			So(nameRegexp.MatchString(cmd.Name), ShouldBeTrue)
+			So(names, ShouldNotContainKey, cmd.Name)
+			names[cmd.Name] = struct{}{}
+
+			So(utf8.RuneCountInString(cmd.Description), ShouldBeBetweenOrEqual, 1, 100)
+
+			optionNames := map[string]struct{}{}
+			sawOptional := false
+			for _, opt := range cmd.Options {
+				So(nameRegexp.MatchString(opt.Name), ShouldBeTrue)
+				So(optionNames, ShouldNotContainKey, opt.Name)
+				optionNames[opt.Name] = struct{}{}
+
+				So(utf8.RuneCountInString(opt.Description), ShouldBeBetweenOrEqual, 1, 100)
+
+				// discord requires required options to come first
+				if !opt.Required {
+					sawOptional = true
+				}
+				So(opt.Required && sawOptional, ShouldBeFalse)
+			}
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/josephcopenhaver/melody-bot/internal/logging"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	"github.com/josephcopenhaver/melody-bot/internal/service/server/reactions"
+)
+
+func (s *Server) addInteractionHandlers(ctx context.Context) {
+	srv := s
+	srv.DiscordSession.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if i.Interaction == nil {
+			return
+		}
+
+		switch i.Type {
+		case discordgo.InteractionMessageComponent:
+			srv.handleMessageComponent(ctx, s, i)
+		case discordgo.InteractionApplicationCommand:
+			srv.handleSlashCommand(ctx, s, i)
+		}
+	})
+}
+
+// indexSlashCommands makes each message handler reachable as a slash command
+func (s *Server) indexSlashCommands() {
+	s.slashCommandHandlers = make(map[string]int, len(s.EventHandlers.MessageCreate))
+
+	for i := range s.EventHandlers.MessageCreate {
+		h := &s.EventHandlers.MessageCreate[i]
+
+		name := h.SlashCommand().Name
+		if _, ok := s.slashCommandHandlers[name]; ok {
+			slog.Error(
+				"code-error: slash command name is not unique",
+				"handler_name", h.Name,
+				"slash_command_name", name,
+			)
+			continue
+		}
+
+		s.slashCommandHandlers[name] = i
+	}
+}
+
+// registerSlashCommands replaces the bot's global application commands with the current set of slash commands
+func (s *Server) registerSlashCommands(ctx context.Context) error {
+	var cmds []*discordgo.ApplicationCommand
+
+	for i := range s.EventHandlers.MessageCreate {
+		h := &s.EventHandlers.MessageCreate[i]
+
+		cmd := h.SlashCommand()
+		if s.slashCommandHandlers[cmd.Name] != i {
+			continue
+		}
+
+		cmds = append(cmds, cmd)
+	}
+
+	if s.DiscordSession.State == nil || s.DiscordSession.State.User == nil {
+		return errors.New("discord session has no user")
+	}
+
+	_, err := s.DiscordSession.ApplicationCommandBulkOverwrite(s.DiscordSession.State.User.ID, "", cmds)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx,
+		"registered slash commands",
+		"count", len(cmds),
+	)
+
+	return nil
+}
+
+// handleSlashCommand runs the message handler a slash command was derived from
+//
+// The invocation is turned back into the text command it stands for, so the
+// handler cannot tell it apart from a mention-based command.
+func (s *Server) handleSlashCommand(ctx context.Context, session *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+
+	idx, ok := s.slashCommandHandlers[data.Name]
+	if !ok {
+		slog.Debug(
+			"unknown slash command",
+			"name", data.Name,
+		)
+		return
+	}
+
+	h := &s.EventHandlers.MessageCreate[idx]
+
+	var user *discordgo.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	} else {
+		user = i.User
+	}
+
+	if user == nil {
+		return
+	}
+
+	var p *service.Player
+	if i.GuildID != "" {
+		p = s.Brain.Player(ctx, &s.wg, session, i.GuildID)
+	}
+
+	text := h.SlashCommandText(session, data)
+
+	logger := slog.With(
+		"guild_id", i.GuildID,
+		"channel_id", i.ChannelID,
+		"interaction_id", i.ID,
+		"author_id", user.ID,
+		"author_username", user.Username,
+		"slash_command", data.Name,
+		"message_content", text,
+	)
+
+	ctxWithLogger := logging.AddToContext(ctx, logger)
+
+	handler := h.Matcher(p, text)
+	if handler == nil {
+		err := session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "command not recognized: `" + text + "`",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			logger.Error(
+				"failed to send default reply",
+				"error", err,
+			)
+		}
+		return
+	}
+
+	// handlers may take longer to finish than discord waits for a response
+	err := session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		logger.Error(
+			"failed to acknowledge slash command",
+			"error", err,
+		)
+		return
+	}
+
+	m := &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ID:        i.ID,
+			ChannelID: i.ChannelID,
+			GuildID:   i.GuildID,
+			Content:   text,
+			Author:    user,
+			Member:    i.Member,
+			Timestamp: time.Now(),
+		},
+	}
+
+	reaction := reactions.StatusOK
+	content := "`" + text + "`"
+
+	if err := handler(ctxWithLogger, session, m, p, s.Brain); err != nil {
+		reaction = reactions.StatusErr
+
+		var v reactions.Reactor
+		if ok := errors.As(err, &v); ok {
+			reaction = v.Reaction()
+		}
+
+		logger.Error(
+			"error in handler",
+			"error", err,
+			"handler_name", h.Name,
+		)
+
+		content += "\nerror: " + err.Error()
+	}
+
+	content = reaction.String() + " " + content
+
+	if _, err := session.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		logger.Error(
+			"failed to reply to slash command",
+			"error", err,
+		)
+	}
+}
+
+func (s *Server) handleMessageComponent(ctx context.Context, session *discordgo.Session, i *discordgo.InteractionCreate) {
+
+	if i.GuildID == "" {
+		return
+	}
+
+	customID := i.MessageComponentData().CustomID
+
+	var userID string
+	if i.Member != nil && i.Member.User != nil {
+		userID = i.Member.User.ID
+	}
+
+	logger := slog.With(
+		"guild_id", i.GuildID,
+		"channel_id", i.ChannelID,
+		"interaction_id", i.ID,
+		"author_id", userID,
+		"custom_id", customID,
+	)
+
+	ctxWithLogger := logging.AddToContext(ctx, logger)
+
+	for _, h := range s.EventHandlers.MessageComponent {
+
+		if !strings.HasPrefix(customID, h.CustomIDPrefix) {
+			continue
+		}
+
+		p := s.Brain.Player(ctx, &s.wg, session, i.GuildID)
+
+		err := h.Handler(ctxWithLogger, session, i, p, customID[len(h.CustomIDPrefix):])
+		if err == nil {
+			return
+		}
+
+		logger.Error(
+			"error in interaction handler",
+			"error", err,
+			"handler_name", h.Name,
+		)
+
+		err = session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "error: " + err.Error(),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			logger.Error(
+				"failed to send error reply",
+				"error", err,
+			)
+		}
+		return
+	}
+
+	logger.Debug(
+		"unhandled message component interaction",
+	)
+}
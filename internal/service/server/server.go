@@ -12,22 +12,34 @@ import (
 )
 
 type EventHandlers struct {
-	MessageCreate []handlers.HandleMessageCreate
+	MessageCreate    []handlers.HandleMessageCreate
+	MessageComponent []handlers.HandleMessageComponent
 }
 
 type Server struct {
-	wg             sync.WaitGroup
-	DiscordSession *discordgo.Session
-	EventHandlers  EventHandlers
-	Brain          *service.Brain
+	wg               sync.WaitGroup
+	DiscordSession   *discordgo.Session
+	EventHandlers    EventHandlers
+	Brain            *service.Brain
+	TranscodeManager *service.TranscodeManager
+	Library          *service.Library
+
+	// slashCommandHandlers maps slash command names to indexes of EventHandlers.MessageCreate
+	slashCommandHandlers map[string]int
 }
 
 func New() *Server {
+	tm := service.NewTranscodeManager()
+	lib := service.NewLibrary()
+
 	return &Server{
 		EventHandlers: EventHandlers{
-			MessageCreate: []handlers.HandleMessageCreate{},
+			MessageCreate:    []handlers.HandleMessageCreate{},
+			MessageComponent: []handlers.HandleMessageComponent{},
 		},
-		Brain: service.NewBrain(),
+		Brain:            service.NewBrain(tm, service.NewYoutubeSearcher(), lib),
+		TranscodeManager: tm,
+		Library:          lib,
 	}
 }
 
@@ -37,14 +49,14 @@ func (s *Server) ListenAndServe(ctx context.Context) (err_result error) {
 		return err
 	}
 
-	sd := handlers.SerialDownloader()
-	sd.Start(ctx)
+	tm := s.TranscodeManager
+	tm.Start(ctx)
 	defer func() {
 		slog.WarnContext(ctx,
-			"waiting for cache downloader to terminate",
+			"waiting for transcode manager to terminate",
 		)
 
-		sd.Wait()
+		tm.Wait()
 	}()
 
 	// open a connection to discord
@@ -67,6 +79,41 @@ func (s *Server) ListenAndServe(ctx context.Context) (err_result error) {
 		s.wg.Wait()
 	}()
 
+	if err := s.registerSlashCommands(ctx); err != nil {
+		slog.ErrorContext(ctx,
+			"failed to register slash commands, only mention-based commands will work",
+			"error", err,
+		)
+	}
+
+	if s.Library.Enabled() {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+
+			// files added while the bot was down are picked up without waiting for a library rescan command
+			result, err := s.Library.Rescan(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.ErrorContext(ctx,
+						"failed to scan library",
+						"error", err,
+					)
+				}
+				return
+			}
+
+			slog.InfoContext(ctx,
+				"scanned library",
+				"added", result.Added,
+				"updated", result.Updated,
+				"removed", result.Removed,
+				"unchanged", result.Unchanged,
+				"failed", result.Failed,
+			)
+		}()
+	}
+
 	slog.InfoContext(ctx,
 		"listening",
 	)
@@ -3,6 +3,7 @@ package server
 import (
 	"github.com/bwmarrin/discordgo"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/josephcopenhaver/melody-bot/internal/service"
 	"github.com/josephcopenhaver/melody-bot/internal/service/config"
 )
 
@@ -14,6 +15,14 @@ func (s *Server) SetConfig(conf *config.Config) error {
 		return err
 	}
 
+	if err := s.TranscodeManager.Configure(conf.TranscodeWorkers, conf.TranscodeQueueSize); err != nil {
+		return err
+	}
+
+	if err := s.Library.Configure(conf.LibraryDir, service.LibraryIndexDir); err != nil {
+		return err
+	}
+
 	return s.ValidateConfig()
 }
 
@@ -21,5 +30,9 @@ func (s *Server) ValidateConfig() error {
 	return validation.ValidateStruct(s,
 		// DiscordSession must not be nil
 		validation.Field(&s.DiscordSession, validation.Required),
+		// TranscodeManager must not be nil
+		validation.Field(&s.TranscodeManager, validation.Required),
+		// Library must not be nil
+		validation.Field(&s.Library, validation.Required),
 	)
 }
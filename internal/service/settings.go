@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/josephcopenhaver/melody-bot/internal/cache"
+)
+
+const (
+	GuildSettingsCacheDir  = ".guild-settings/v1"
+	GuildSettingsCacheSize = 1024
+)
+
+const (
+	DefaultPrefetchCount = 2
+	MaxPrefetchCount     = 10
+	DefaultVolume        = 100
+	MaxVolume            = 200
+)
+
+// GuildSettings are player preferences that are remembered per guild across restarts
+type GuildSettings struct {
+	PrefetchCount int `json:"prefetch_count"`
+	Volume        int `json:"volume"`
+	// AnnounceUpNext adds the following track to the now playing broadcast, it is
+	// off by default so track change broadcasts stay the same until a guild opts in
+	AnnounceUpNext bool `json:"announce_up_next"`
+}
+
+func DefaultGuildSettings() GuildSettings {
+	return GuildSettings{
+		PrefetchCount:  DefaultPrefetchCount,
+		Volume:         DefaultVolume,
+		AnnounceUpNext: false,
+	}
+}
+
+func (gs *GuildSettings) validate() error {
+	if gs.PrefetchCount < 0 || gs.PrefetchCount > MaxPrefetchCount {
+		return fmt.Errorf("prefetch count must be between 0 and %d", MaxPrefetchCount)
+	}
+
+	if gs.Volume < 0 || gs.Volume > MaxVolume {
+		return fmt.Errorf("volume must be between 0 and %d", MaxVolume)
+	}
+
+	return nil
+}
+
+var guildSettingsCacheOptions = []cache.DiskCacheOption[string, GuildSettings]{
+	cache.DiskCacheKeyMarshaler[string, GuildSettings](cache.NewKeyMarshaler(
+		func(s string) ([]byte, error) {
+			return []byte(s), nil
+		},
+	)),
+	cache.DiskCacheValueTranscoder[string](cache.NewTranscoder(
+		func(v GuildSettings) ([]byte, error) {
+			var buf bytes.Buffer
+
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+
+			if err := enc.Encode(v); err != nil {
+				return nil, err
+			}
+
+			return buf.Bytes(), nil
+		},
+		func(b []byte) (GuildSettings, error) {
+			var result GuildSettings
+
+			// settings added after a record was saved keep their default values
+			buf := DefaultGuildSettings()
+			if err := json.Unmarshal(b, &buf); err != nil {
+				return result, err
+			}
+
+			result = buf
+			return result, nil
+		},
+	)),
+}
+
+var guildSettingsCache *cache.DiskCache[string, GuildSettings]
+
+// guildSettingsMutex serializes read-modify-write cycles of guild settings
+var guildSettingsMutex sync.Mutex
+
+//nolint:gochecknoinits
+func init() {
+	v, err := cache.NewDiskCache(GuildSettingsCacheDir, GuildSettingsCacheSize, guildSettingsCacheOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	guildSettingsCache = v
+}
+
+func loadGuildSettings(guildID string) GuildSettings {
+	v, ok, err := guildSettingsCache.Get(guildID)
+	if err != nil {
+		slog.Error(
+			"failed to load guild settings, using defaults",
+			"error", err,
+			"guild_id", guildID,
+		)
+		return DefaultGuildSettings()
+	}
+
+	if !ok {
+		return DefaultGuildSettings()
+	}
+
+	return v
+}
+
+func updateGuildSettings(guildID string, f func(*GuildSettings)) (GuildSettings, error) {
+	guildSettingsMutex.Lock()
+	defer guildSettingsMutex.Unlock()
+
+	v := loadGuildSettings(guildID)
+	f(&v)
+
+	if err := v.validate(); err != nil {
+		return loadGuildSettings(guildID), err
+	}
+
+	if err := guildSettingsCache.Set(guildID, v); err != nil {
+		return v, fmt.Errorf("failed to save guild settings: %w", err)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"math/rand/v2"
+)
+
+// maxPlayHistory is the number of played tracks remembered so previous can walk back through them
+const maxPlayHistory = 256
+
+// recordPlayed remembers the current track has started playing
+func (m *PlayerMemory) recordPlayed() {
+	url := m.tracks[m.currentTrackIdx].SrcUrlStr()
+
+	if m.played == nil {
+		m.played = map[string]struct{}{}
+	}
+	m.played[url] = struct{}{}
+
+	// restarting a track does not add to the history
+	if n := len(m.history); n > 0 && m.history[n-1] == url {
+		return
+	}
+
+	if len(m.history) >= maxPlayHistory {
+		copy(m.history, m.history[1:])
+		m.history = m.history[:len(m.history)-1]
+	}
+
+	m.history = append(m.history, url)
+}
+
+// pickShuffleTrackIdx returns the index of a random track that has not been played
+// in the current shuffle cycle, or -1 when every track has been played
+func (m *PlayerMemory) pickShuffleTrackIdx() int {
+	var candidates []int
+
+	for i, t := range m.tracks {
+		if i == m.currentTrackIdx && len(m.tracks) > 1 {
+			continue
+		}
+
+		if _, ok := m.played[t.SrcUrlStr()]; ok {
+			continue
+		}
+
+		candidates = append(candidates, i)
+	}
+
+	if len(candidates) == 0 {
+		return -1
+	}
+
+	return candidates[rand.IntN(len(candidates))]
+}
+
+// planShuffle chooses the track that plays after the current one in shuffle mode
+//
+// Choosing ahead of time lets the next track be prefetched.
+func (m *PlayerMemory) planShuffle() {
+	m.shuffleNext = ""
+
+	// tracks added by play next requests play in order before shuffling resumes
+	if i := m.playNextTrackIdx(); i != -1 {
+		m.shuffleNext = m.tracks[i].SrcUrlStr()
+		return
+	}
+
+	i := m.pickShuffleTrackIdx()
+	if i == -1 {
+		if m.repeatMode == RepeatModeOff {
+			return
+		}
+
+		// every track has been heard, start a new cycle
+		m.played = nil
+
+		i = m.pickShuffleTrackIdx()
+		if i == -1 {
+			return
+		}
+	}
+
+	m.shuffleNext = m.tracks[i].SrcUrlStr()
+}
+
+// nextShuffleTrackIdx returns the index of the planned shuffle track, or -1
+// when every track has been heard and the playlist is not repeating
+func (m *PlayerMemory) nextShuffleTrackIdx() int {
+	if m.shuffleNext != "" {
+		if i := m.indexOfTrack(m.shuffleNext); i != -1 {
+			return i
+		}
+	}
+
+	// planned track was removed or never planned
+	m.planShuffle()
+	if m.shuffleNext == "" {
+		return -1
+	}
+
+	return m.indexOfTrack(m.shuffleNext)
+}
+
+// shuffleUpcoming randomizes the order of the tracks after the current track
+func (m *PlayerMemory) shuffleUpcoming() int {
+	start := m.currentTrackIdx + 1
+	if m.pinnedNext {
+		// keep the restarted or previous track next in line
+		start++
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start >= len(m.tracks) {
+		return 0
+	}
+
+	// earlier play next requests are shuffled in with the rest of the queue
+	m.playNextUrl = ""
+
+	upcoming := m.tracks[start:]
+	rand.Shuffle(len(upcoming), func(i, j int) {
+		upcoming[i], upcoming[j] = upcoming[j], upcoming[i]
+	})
+
+	return len(upcoming)
+}
+
+// previousFromHistory moves playback back through the play history
+//
+// Returns false if the history does not contain a track that is still in the playlist.
+func (m *PlayerMemory) previousFromHistory(currentIsPlaying bool) bool {
+	if currentIsPlaying || m.pinnedNext {
+		// the last entry is the track that is playing or about to replay
+		if n := len(m.history); n > 0 {
+			m.history = m.history[:n-1]
+		}
+	}
+
+	for n := len(m.history); n > 0; n = len(m.history) {
+		url := m.history[n-1]
+
+		// the entry is added back once the track starts playing again
+		m.history = m.history[:n-1]
+
+		if i := m.indexOfTrack(url); i != -1 {
+			m.currentTrackIdx = i - 1
+			m.pinnedNext = true
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *PlayerMemory) shuffleModeDescription() string {
+	if m.shuffle {
+		return "shuffling"
+	}
+
+	return "not shuffling"
+}
+
+// ShuffleUpcoming randomizes the order of the tracks after the current track
+//
+// Returns the number of tracks shuffled.
+func (p *Player) ShuffleUpcoming() int {
+	var result int
+
+	p.withMemory(func(m *PlayerMemory) {
+		result = m.shuffleUpcoming()
+
+		if m.shuffle {
+			m.planShuffle()
+		}
+	})
+
+	return result
+}
+
+// SetShuffleMode turns shuffle playback mode on or off
+//
+// In shuffle mode each track is picked at random from the tracks that have not
+// been played yet until the whole playlist has been heard.
+func (p *Player) SetShuffleMode(on bool) string {
+	var result string
+
+	p.withMemory(func(m *PlayerMemory) {
+		if m.shuffle != on {
+			m.shuffle = on
+			m.played = nil
+			m.shuffleNext = ""
+
+			if on {
+				if m.currentTrackIdx >= 0 && m.currentTrackIdx < len(m.tracks) {
+					m.played = map[string]struct{}{
+						m.tracks[m.currentTrackIdx].SrcUrlStr(): {},
+					}
+				}
+
+				m.planShuffle()
+			}
+		}
+
+		result = m.shuffleModeDescription()
+	})
+
+	p.notifyChanged()
+
+	return result
+}
+
+func (p *Player) Shuffling() bool {
+	var result bool
+
+	p.withMemory(func(m *PlayerMemory) {
+		result = m.shuffle
+	})
+
+	return result
+}
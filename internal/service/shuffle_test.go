@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestShuffle(t *testing.T) {
+	urls := []string{"a", "b", "c", "d", "e"}
+
+	// playCycle plays one track per url and returns the sorted urls of the tracks played
+	playCycle := func(p *service.Player) []string {
+		var played []string
+		for range urls {
+			played = append(played, p.NextTestTrack())
+		}
+
+		slices.Sort(played)
+		return played
+	}
+
+	Convey("every track plays once before any track repeats", t, func() {
+		for range 20 {
+			p := service.NewPlaylistTestPlayer(urls...)
+			p.SetRepeatMode(service.RepeatModeAll)
+			p.SetShuffleMode(true)
+
+			for range 3 {
+				So(playCycle(p), ShouldResemble, urls)
+			}
+		}
+	})
+
+	Convey("shuffling stops once every track has played when not repeating", t, func() {
+		p := service.NewPlaylistTestPlayer(urls...)
+		p.SetRepeatMode(service.RepeatModeOff)
+		p.SetShuffleMode(true)
+
+		So(playCycle(p), ShouldResemble, urls)
+		So(p.NextTestTrack(), ShouldBeEmpty)
+	})
+
+	Convey("previous walks back through the shuffled play history", t, func() {
+		p := service.NewPlaylistTestPlayer(urls...)
+		p.SetShuffleMode(true)
+
+		played := []string{p.NextTestTrack(), p.NextTestTrack(), p.NextTestTrack()}
+
+		p.PreviousTestTrack()
+		So(p.NextTestTrack(), ShouldEqual, played[1])
+
+		p.PreviousTestTrack()
+		So(p.NextTestTrack(), ShouldEqual, played[0])
+
+		// going back does not count as hearing a track again
+		played = append(played, p.NextTestTrack(), p.NextTestTrack())
+		slices.Sort(played)
+		So(played, ShouldResemble, urls)
+	})
+}
@@ -6,6 +6,9 @@ import (
 
 func New() (*config.Config, error) {
 
-	conf := &config.Config{}
+	conf := &config.Config{
+		TranscodeWorkers:   1,
+		TranscodeQueueSize: 128,
+	}
 	return conf, nil
 }
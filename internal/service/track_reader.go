@@ -0,0 +1,74 @@
+package service
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrRewindWhileTranscoding = errors.New("cannot rewind a track that is still being downloaded")
+
+// trackReader reads pcm frames of a track and keeps track of the playback position
+type trackReader struct {
+	f   io.ReadCloser
+	br  *bufio.Reader
+	pos int64
+}
+
+func newTrackReader(f io.ReadCloser) *trackReader {
+	return &trackReader{
+		f:  f,
+		br: bufio.NewReaderSize(f, SampleMaxBytes),
+	}
+}
+
+func (r *trackReader) readFrame(pcmBuf *[SampleSize]int16) error {
+	if err := binary.Read(r.br, binary.LittleEndian, pcmBuf); err != nil {
+		return err
+	}
+
+	r.pos += SampleMaxBytes
+
+	return nil
+}
+
+// frame returns the number of frames read or skipped so far
+func (r *trackReader) frame() int64 {
+	return r.pos / SampleMaxBytes
+}
+
+func (r *trackReader) position() time.Duration {
+	return BytesToDuration(r.pos)
+}
+
+// seek moves playback to d from the start of the track
+//
+// Streams that cannot seek can only move forward by reading ahead. Streams
+// that are still being transcoded just in time fail with ErrSeekAheadOfTranscode
+// when seeking past what has been written so far. Moving past the end of the
+// stream returns io.EOF.
+func (r *trackReader) seek(d time.Duration) error {
+	target := durationToBytes(d)
+
+	if s, ok := r.f.(io.Seeker); ok {
+		if _, err := s.Seek(target, io.SeekStart); err != nil {
+			return err
+		}
+
+		r.br.Reset(r.f)
+		r.pos = target
+
+		return nil
+	}
+
+	if target < r.pos {
+		return ErrRewindWhileTranscoding
+	}
+
+	n, err := io.CopyN(io.Discard, r.br, target-r.pos)
+	r.pos += n
+
+	return err
+}
@@ -0,0 +1,533 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTranscodeWorkers   = 1
+	DefaultTranscodeQueueSize = 128
+)
+
+var (
+	ErrTranscodeManagerStarted    = errors.New("transcode manager already started")
+	ErrTranscodeManagerNotRunning = errors.New("transcode manager is not running")
+	ErrPanicInTranscodeJob        = errors.New("panic in transcode job")
+	ErrTranscodeJobPreempted      = errors.New("transcode job preempted by an interactive job")
+)
+
+// TranscodePriority: higher priority jobs are always started before lower priority jobs
+type TranscodePriority int8
+
+const (
+	TranscodePriorityUnusedLower TranscodePriority = iota - 1
+	//
+	TranscodePriorityBackground
+	TranscodePriorityPlaylist
+	TranscodePriorityNextUp
+	TranscodePriorityNowPlaying
+	//
+	TranscodePriorityUnusedUpper
+)
+
+// interactive reports if a user is waiting on jobs of this priority to hear a track
+func (p TranscodePriority) interactive() bool {
+	return p >= TranscodePriorityNextUp
+}
+
+func (p TranscodePriority) String() string {
+	return []string{
+		"background",
+		"playlist",
+		"next-up",
+		"now-playing",
+	}[int(p)]
+}
+
+// TranscodeRequest describes a unit of download and/or transcode work
+//
+// Key identifies the media being processed so a queued job can be reprioritized.
+type TranscodeRequest struct {
+	GuildID     string
+	Key         string
+	Description string
+	Priority    TranscodePriority
+	Run         func(context.Context)
+}
+
+type transcodeJob struct {
+	id            uint64
+	guildID       string
+	key           string
+	description   string
+	priority      TranscodePriority
+	run           func()
+	cancel        context.CancelCauseFunc
+	holdsCapacity bool
+	preempted     bool
+	enqueuedAt    time.Time
+	startedAt     time.Time
+}
+
+func (j *transcodeJob) status() TranscodeJobStatus {
+	return TranscodeJobStatus{
+		GuildID:     j.guildID,
+		Description: j.description,
+		Priority:    j.priority,
+		EnqueuedAt:  j.enqueuedAt,
+		StartedAt:   j.startedAt,
+	}
+}
+
+// runsBefore reports if job j should be started before job o
+func (j *transcodeJob) runsBefore(o *transcodeJob, runningByGuild map[string]int) bool {
+	if j.priority != o.priority {
+		return j.priority > o.priority
+	}
+
+	if jr, or := runningByGuild[j.guildID], runningByGuild[o.guildID]; jr != or {
+		return jr < or
+	}
+
+	return j.id < o.id
+}
+
+type TranscodeJobStatus struct {
+	GuildID     string
+	Description string
+	Priority    TranscodePriority
+	EnqueuedAt  time.Time
+	StartedAt   time.Time
+}
+
+type TranscodeStatus struct {
+	NumWorkers   int
+	MaxQueueSize int
+	Running      []TranscodeJobStatus
+	Queued       []TranscodeJobStatus
+}
+
+// TranscodeManager runs download and transcode jobs on a bounded pool of workers
+//
+// When picking the next job to run, the highest priority job wins. Amongst jobs of
+// equal priority, guilds with the fewest running jobs are preferred so one busy
+// guild cannot starve the others.
+//
+// Interactive jobs (now playing and next up) never wait for room in the queue,
+// and when every worker is busy a running background job is canceled with
+// ErrTranscodeJobPreempted to make room for them.
+type TranscodeManager struct {
+	rwm          sync.RWMutex
+	wg           sync.WaitGroup
+	startedAt    time.Time
+	stopped      bool
+	numWorkers   int
+	maxQueueSize int
+	nextJobID    uint64
+	queue        []*transcodeJob
+	running      map[uint64]*transcodeJob
+	capacity     chan struct{}
+	ready        chan struct{}
+	done         chan struct{}
+}
+
+func NewTranscodeManager() *TranscodeManager {
+	return &TranscodeManager{
+		numWorkers:   DefaultTranscodeWorkers,
+		maxQueueSize: DefaultTranscodeQueueSize,
+		running:      map[uint64]*transcodeJob{},
+	}
+}
+
+// Configure sets the number of concurrent workers and the max number of queued jobs
+//
+// It must be called before Start.
+func (tm *TranscodeManager) Configure(numWorkers, maxQueueSize int) error {
+	if numWorkers < 1 {
+		return fmt.Errorf("transcode worker count must be positive: %d", numWorkers)
+	}
+
+	if maxQueueSize < 1 {
+		return fmt.Errorf("transcode queue size must be positive: %d", maxQueueSize)
+	}
+
+	tm.rwm.Lock()
+	defer tm.rwm.Unlock()
+
+	if !tm.startedAt.IsZero() {
+		return ErrTranscodeManagerStarted
+	}
+
+	tm.numWorkers = numWorkers
+	tm.maxQueueSize = maxQueueSize
+
+	return nil
+}
+
+func (tm *TranscodeManager) Start(ctx context.Context) {
+	tm.rwm.Lock()
+	defer tm.rwm.Unlock()
+
+	if !tm.startedAt.IsZero() {
+		return
+	}
+
+	tm.startedAt = time.Now()
+	tm.capacity = make(chan struct{}, tm.maxQueueSize)
+	tm.ready = make(chan struct{}, tm.numWorkers)
+	tm.done = make(chan struct{})
+
+	tm.wg.Add(1)
+	go func() {
+		defer tm.wg.Done()
+
+		<-ctx.Done()
+
+		tm.stop()
+	}()
+
+	var workersWG sync.WaitGroup
+	workersWG.Add(tm.numWorkers)
+	for i := 0; i < tm.numWorkers; i++ {
+		go func() {
+			defer workersWG.Done()
+
+			tm.worker(ctx)
+		}()
+	}
+
+	tm.wg.Add(1)
+	go func() {
+		defer tm.wg.Done()
+
+		workersWG.Wait()
+
+		// jobs that never started are still run so they can observe
+		// their context is done and release any resources they hold
+		for _, j := range tm.drain() {
+			tm.runJob(ctx, j)
+		}
+	}()
+}
+
+func (tm *TranscodeManager) Wait() {
+	tm.wg.Wait()
+}
+
+// Enqueue blocks until there is room in the queue for the request, ctx is done, or the manager stops
+//
+// Interactive requests never block as they do not count against the queue size.
+//
+// Once Enqueue returns without error the request's Run func is guaranteed to be
+// called exactly once with a child context of ctx, even if ctx is done or the
+// manager stops before the job is started. The child context is canceled when
+// the manager stops.
+func (tm *TranscodeManager) Enqueue(ctx context.Context, r TranscodeRequest) error {
+	if r.Run == nil {
+		return nil
+	}
+
+	if r.Priority <= TranscodePriorityUnusedLower || r.Priority >= TranscodePriorityUnusedUpper {
+		return fmt.Errorf("invalid transcode priority: %d", r.Priority)
+	}
+
+	tm.rwm.RLock()
+	capacity := tm.capacity
+	done := tm.done
+	tm.rwm.RUnlock()
+
+	if capacity == nil {
+		return ErrTranscodeManagerNotRunning
+	}
+
+	holdsCapacity := !r.Priority.interactive()
+	if holdsCapacity {
+		ctxDone := ctx.Done()
+		select {
+		case <-ctxDone:
+			return ctx.Err()
+		case <-done:
+			return ErrTranscodeManagerNotRunning
+		case capacity <- struct{}{}:
+		}
+	}
+
+	tm.rwm.Lock()
+	defer tm.rwm.Unlock()
+
+	if tm.stopped {
+		if holdsCapacity {
+			<-capacity
+		}
+		return ErrTranscodeManagerNotRunning
+	}
+
+	tm.nextJobID++
+	f := r.Run
+	ctx, cancel := context.WithCancelCause(ctx)
+	tm.queue = append(tm.queue, &transcodeJob{
+		id:          tm.nextJobID,
+		guildID:     r.GuildID,
+		key:         r.Key,
+		description: r.Description,
+		priority:    r.Priority,
+		run: func() {
+			defer cancel(nil)
+
+			f(ctx)
+		},
+		cancel:        cancel,
+		holdsCapacity: holdsCapacity,
+		enqueuedAt:    time.Now(),
+	})
+
+	// a dropped signal is fine: every worker empties the queue before it waits for another
+	select {
+	case tm.ready <- struct{}{}:
+	default:
+	}
+
+	tm.preemptBackground()
+
+	return nil
+}
+
+func (tm *TranscodeManager) Status() TranscodeStatus {
+	tm.rwm.RLock()
+	defer tm.rwm.RUnlock()
+
+	result := TranscodeStatus{
+		NumWorkers:   tm.numWorkers,
+		MaxQueueSize: tm.maxQueueSize,
+		Running:      make([]TranscodeJobStatus, 0, len(tm.running)),
+		Queued:       make([]TranscodeJobStatus, 0, len(tm.queue)),
+	}
+
+	for _, j := range tm.running {
+		result.Running = append(result.Running, j.status())
+	}
+
+	sort.Slice(result.Running, func(i, j int) bool {
+		return result.Running[i].StartedAt.Before(result.Running[j].StartedAt)
+	})
+
+	queue := make([]*transcodeJob, len(tm.queue))
+	copy(queue, tm.queue)
+
+	runningByGuild := tm.runningByGuild()
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].runsBefore(queue[j], runningByGuild)
+	})
+
+	for _, j := range queue {
+		result.Queued = append(result.Queued, j.status())
+	}
+
+	return result
+}
+
+// Reprioritize changes the priority of a guild's queued jobs
+//
+// Queued jobs of the guild with a key in priorities take on the associated priority.
+// All other queued jobs of the guild that are prioritized above playlist jobs are
+// demoted to playlist priority as they are no longer playing or up next.
+func (tm *TranscodeManager) Reprioritize(guildID string, priorities map[string]TranscodePriority) {
+	tm.rwm.Lock()
+	defer tm.rwm.Unlock()
+
+	for _, j := range tm.queue {
+		if j.guildID != guildID {
+			continue
+		}
+
+		if v, ok := priorities[j.key]; ok {
+			j.priority = v
+			continue
+		}
+
+		if j.priority > TranscodePriorityPlaylist {
+			j.priority = TranscodePriorityPlaylist
+		}
+	}
+
+	tm.preemptBackground()
+}
+
+// preemptBackground cancels a running background job for each interactive job
+// that is waiting on a busy worker
+//
+// The caller must hold the write lock.
+func (tm *TranscodeManager) preemptBackground() {
+	if len(tm.running) < tm.numWorkers {
+		return
+	}
+
+	var waiting int
+	for _, j := range tm.queue {
+		if j.priority.interactive() {
+			waiting++
+		}
+	}
+
+	var victim *transcodeJob
+	for _, j := range tm.running {
+		if j.priority != TranscodePriorityBackground {
+			continue
+		}
+
+		if j.preempted {
+			waiting--
+			continue
+		}
+
+		if victim == nil || j.startedAt.After(victim.startedAt) {
+			victim = j
+		}
+	}
+
+	if victim == nil || waiting <= 0 {
+		return
+	}
+
+	victim.preempted = true
+	victim.cancel(ErrTranscodeJobPreempted)
+}
+
+func (tm *TranscodeManager) runningByGuild() map[string]int {
+	result := make(map[string]int, len(tm.running))
+	for _, j := range tm.running {
+		result[j.guildID]++
+	}
+
+	return result
+}
+
+func (tm *TranscodeManager) worker(ctx context.Context) {
+	ctxDone := ctx.Done()
+
+	for {
+		select {
+		case <-ctxDone:
+			return
+		default:
+		}
+		select {
+		case <-ctxDone:
+			return
+		case <-tm.ready:
+			for ctx.Err() == nil {
+				j := tm.dequeue()
+				if j == nil {
+					break
+				}
+
+				tm.runJob(ctx, j)
+
+				tm.rwm.Lock()
+				delete(tm.running, j.id)
+				tm.rwm.Unlock()
+			}
+		}
+	}
+}
+
+// dequeue removes the next job to run from the queue and marks it as running
+func (tm *TranscodeManager) dequeue() *transcodeJob {
+	tm.rwm.Lock()
+	defer tm.rwm.Unlock()
+
+	if len(tm.queue) == 0 {
+		return nil
+	}
+
+	runningByGuild := tm.runningByGuild()
+
+	idx := 0
+	for i, j := range tm.queue {
+		if j.runsBefore(tm.queue[idx], runningByGuild) {
+			idx = i
+		}
+	}
+
+	j := tm.queue[idx]
+	copy(tm.queue[idx:], tm.queue[idx+1:])
+	tm.queue[len(tm.queue)-1] = nil
+	tm.queue = tm.queue[:len(tm.queue)-1]
+
+	// never blocks: a capacity token was taken when the job was enqueued
+	if j.holdsCapacity {
+		<-tm.capacity
+	}
+
+	j.startedAt = time.Now()
+	tm.running[j.id] = j
+
+	return j
+}
+
+// stop prevents new jobs from being accepted and cancels all running and queued jobs
+func (tm *TranscodeManager) stop() {
+	tm.rwm.Lock()
+	defer tm.rwm.Unlock()
+
+	tm.stopped = true
+	close(tm.done)
+
+	for _, j := range tm.running {
+		j.cancel(nil)
+	}
+
+	for _, j := range tm.queue {
+		j.cancel(nil)
+	}
+}
+
+// drain stops the manager from accepting new jobs and returns all jobs that never started
+//
+// The capacity tokens of the drained jobs are given back so the queue is empty
+// in every sense once the manager has stopped. A stopped manager is never
+// started again: Start is a no-op after the first call.
+func (tm *TranscodeManager) drain() []*transcodeJob {
+	tm.rwm.Lock()
+	defer tm.rwm.Unlock()
+
+	tm.stopped = true
+
+	result := tm.queue
+	tm.queue = nil
+
+	for _, j := range result {
+		if j.holdsCapacity {
+			// never blocks: a capacity token was taken when the job was enqueued
+			<-tm.capacity
+			j.holdsCapacity = false
+		}
+	}
+
+	return result
+}
+
+func (tm *TranscodeManager) runJob(ctx context.Context, j *transcodeJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				err = ErrPanicInTranscodeJob
+			}
+			slog.ErrorContext(ctx,
+				"panic in transcode job",
+				"error", err,
+				"guild_id", j.guildID,
+				"description", j.description,
+			)
+		}
+	}()
+
+	j.run()
+}
@@ -0,0 +1,265 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTranscodeManager(t *testing.T) {
+	Convey("jobs never run on more workers than configured", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tm := service.NewTranscodeManager()
+		So(tm.Configure(2, 16), ShouldBeNil)
+		tm.Start(ctx)
+
+		var running, maxRunning atomic.Int32
+		var wg sync.WaitGroup
+		release := make(chan struct{})
+
+		const numJobs = 8
+		wg.Add(numJobs)
+		for i := 0; i < numJobs; i++ {
+			err := tm.Enqueue(ctx, service.TranscodeRequest{
+				GuildID: "guild",
+				Run: func(context.Context) {
+					defer wg.Done()
+
+					n := running.Add(1)
+					defer running.Add(-1)
+
+					for {
+						v := maxRunning.Load()
+						if n <= v || maxRunning.CompareAndSwap(v, n) {
+							break
+						}
+					}
+
+					<-release
+				},
+			})
+			So(err, ShouldBeNil)
+		}
+
+		close(release)
+		wg.Wait()
+
+		So(maxRunning.Load(), ShouldBeLessThanOrEqualTo, 2)
+
+		cancel()
+		tm.Wait()
+	})
+
+	Convey("queued jobs still run once the manager stops", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		tm := service.NewTranscodeManager()
+		So(tm.Configure(1, 4), ShouldBeNil)
+		tm.Start(ctx)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		So(tm.Enqueue(ctx, service.TranscodeRequest{
+			Run: func(context.Context) {
+				close(started)
+				<-release
+			},
+		}), ShouldBeNil)
+
+		<-started
+
+		var ranWithDoneCtx atomic.Bool
+		So(tm.Enqueue(ctx, service.TranscodeRequest{
+			Run: func(ctx context.Context) {
+				ranWithDoneCtx.Store(ctx.Err() != nil)
+			},
+		}), ShouldBeNil)
+
+		cancel()
+		close(release)
+		tm.Wait()
+
+		So(ranWithDoneCtx.Load(), ShouldBeTrue)
+		So(tm.Enqueue(context.Background(), service.TranscodeRequest{Run: func(context.Context) {}}), ShouldEqual, service.ErrTranscodeManagerNotRunning)
+	})
+
+	Convey("higher priority jobs start first and can be reprioritized", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tm := service.NewTranscodeManager()
+		So(tm.Configure(1, 8), ShouldBeNil)
+		tm.Start(ctx)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		So(tm.Enqueue(ctx, service.TranscodeRequest{
+			Run: func(context.Context) {
+				close(started)
+				<-release
+			},
+		}), ShouldBeNil)
+
+		<-started
+
+		var mutex sync.Mutex
+		var order []string
+		var wg sync.WaitGroup
+		enqueue := func(key string, priority service.TranscodePriority) {
+			wg.Add(1)
+			So(tm.Enqueue(ctx, service.TranscodeRequest{
+				GuildID:  "guild",
+				Key:      key,
+				Priority: priority,
+				Run: func(context.Context) {
+					defer wg.Done()
+
+					mutex.Lock()
+					defer mutex.Unlock()
+
+					order = append(order, key)
+				},
+			}), ShouldBeNil)
+		}
+
+		enqueue("cache", service.TranscodePriorityBackground)
+		enqueue("playlist-1", service.TranscodePriorityPlaylist)
+		enqueue("playlist-2", service.TranscodePriorityPlaylist)
+		enqueue("playing", service.TranscodePriorityNowPlaying)
+
+		// "playing" is no longer the current track so it is demoted to playlist priority
+		tm.Reprioritize("guild", map[string]service.TranscodePriority{
+			"playlist-2": service.TranscodePriorityNextUp,
+		})
+
+		close(release)
+		wg.Wait()
+
+		So(order, ShouldResemble, []string{"playlist-2", "playlist-1", "playing", "cache"})
+	})
+
+	Convey("a now playing job runs next when it arrives while a lower priority job occupies the only worker", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tm := service.NewTranscodeManager()
+		So(tm.Configure(1, 8), ShouldBeNil)
+		tm.Start(ctx)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		So(tm.Enqueue(ctx, service.TranscodeRequest{
+			GuildID:  "guild-a",
+			Key:      "cache",
+			Priority: service.TranscodePriorityBackground,
+			Run: func(context.Context) {
+				close(started)
+				<-release
+			},
+		}), ShouldBeNil)
+
+		<-started
+
+		var mutex sync.Mutex
+		var order []string
+		var wg sync.WaitGroup
+		enqueue := func(guildID, key string, priority service.TranscodePriority) {
+			wg.Add(1)
+			So(tm.Enqueue(ctx, service.TranscodeRequest{
+				GuildID:  guildID,
+				Key:      key,
+				Priority: priority,
+				Run: func(context.Context) {
+					defer wg.Done()
+
+					mutex.Lock()
+					defer mutex.Unlock()
+
+					order = append(order, key)
+				},
+			}), ShouldBeNil)
+		}
+
+		enqueue("guild-a", "next-up", service.TranscodePriorityNextUp)
+		enqueue("guild-a", "playlist", service.TranscodePriorityPlaylist)
+		enqueue("guild-b", "playing", service.TranscodePriorityNowPlaying)
+
+		status := tm.Status()
+		So(len(status.Running), ShouldEqual, 1)
+		So(status.Queued[0].Priority, ShouldEqual, service.TranscodePriorityNowPlaying)
+
+		close(release)
+		wg.Wait()
+
+		So(order, ShouldResemble, []string{"playing", "next-up", "playlist"})
+	})
+
+	Convey("a now playing job never waits on a full queue and preempts a running background job", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tm := service.NewTranscodeManager()
+		So(tm.Configure(1, 2), ShouldBeNil)
+		tm.Start(ctx)
+
+		started := make(chan struct{})
+		preempted := make(chan error, 1)
+		So(tm.Enqueue(ctx, service.TranscodeRequest{
+			GuildID:  "guild-a",
+			Key:      "cache-running",
+			Priority: service.TranscodePriorityBackground,
+			Run: func(ctx context.Context) {
+				close(started)
+				<-ctx.Done()
+				preempted <- context.Cause(ctx)
+			},
+		}), ShouldBeNil)
+
+		<-started
+
+		var mutex sync.Mutex
+		var order []string
+		var wg sync.WaitGroup
+		enqueue := func(guildID, key string, priority service.TranscodePriority) {
+			wg.Add(1)
+			So(tm.Enqueue(ctx, service.TranscodeRequest{
+				GuildID:  guildID,
+				Key:      key,
+				Priority: priority,
+				Run: func(context.Context) {
+					defer wg.Done()
+
+					mutex.Lock()
+					defer mutex.Unlock()
+
+					order = append(order, key)
+				},
+			}), ShouldBeNil)
+		}
+
+		enqueue("guild-a", "cache-1", service.TranscodePriorityBackground)
+		enqueue("guild-a", "cache-2", service.TranscodePriorityBackground)
+
+		// the queue is full so another background job must wait for room
+		fullCtx, fullCancel := context.WithCancel(ctx)
+		fullCancel()
+		So(tm.Enqueue(fullCtx, service.TranscodeRequest{
+			Priority: service.TranscodePriorityBackground,
+			Run:      func(context.Context) {},
+		}), ShouldEqual, context.Canceled)
+
+		enqueue("guild-b", "playing", service.TranscodePriorityNowPlaying)
+
+		So(<-preempted, ShouldEqual, service.ErrTranscodeJobPreempted)
+
+		wg.Wait()
+
+		So(order, ShouldResemble, []string{"playing", "cache-1", "cache-2"})
+	})
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// transcodingFilePollInterval is how often a reader that caught up with a transcode checks for more data
+const transcodingFilePollInterval = 20 * time.Millisecond
+
+var (
+	ErrTranscodingFileNotStarted = errors.New("transcode finished without providing a file")
+	ErrSeekAheadOfTranscode      = errors.New("cannot seek past the part of the track transcoded so far")
+)
+
+// TranscodingFile reads a file that a transcode job may still be writing
+//
+// Reads that catch up with the job wait for it to write more, so a track can be
+// played while it is transcoded without the job ever waiting on playback. Once
+// the job finishes the file reads and seeks like any other file.
+type TranscodingFile struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	startOnce sync.Once
+	started   chan struct{}
+	// f is set before started is closed, it stays nil when the job fails to start
+	f *os.File
+
+	finishOnce sync.Once
+	done       chan struct{}
+	// err is set before done is closed
+	err error
+}
+
+// NewTranscodingFile returns a file to read and the context the transcode job must run with
+//
+// The context is canceled when the file is closed.
+func NewTranscodingFile(ctx context.Context) (*TranscodingFile, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &TranscodingFile{
+		ctx:     ctx,
+		cancel:  cancel,
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}, ctx
+}
+
+// Start is called by the job once filePath exists and is about to be written
+func (tf *TranscodingFile) Start(filePath string) error {
+	var err error
+	tf.startOnce.Do(func() {
+		defer close(tf.started)
+
+		var f *os.File
+		f, err = os.Open(filePath)
+		if err != nil {
+			return
+		}
+
+		tf.f = f
+	})
+
+	return err
+}
+
+// Finish is called by the job once it stops writing the file, err being the result of the job
+func (tf *TranscodingFile) Finish(err error) {
+	tf.finishOnce.Do(func() {
+		tf.startOnce.Do(func() {
+			close(tf.started)
+		})
+
+		if err == nil && tf.f == nil {
+			err = ErrTranscodingFileNotStarted
+		}
+
+		tf.err = err
+		close(tf.done)
+	})
+}
+
+// finished returns true and the result of the job once the job has finished
+func (tf *TranscodingFile) finished() (bool, error) {
+	select {
+	case <-tf.done:
+		return true, tf.err
+	default:
+		return false, nil
+	}
+}
+
+// waitStarted waits for the job to provide the file, returning the job's error when it never does
+func (tf *TranscodingFile) waitStarted() error {
+	select {
+	case <-tf.ctx.Done():
+		return tf.ctx.Err()
+	case <-tf.started:
+	}
+
+	if tf.f != nil {
+		return nil
+	}
+
+	<-tf.done
+	return tf.err
+}
+
+// waitProgress waits for the job to write more or finish
+func (tf *TranscodingFile) waitProgress() error {
+	t := time.NewTimer(transcodingFilePollInterval)
+	defer t.Stop()
+
+	select {
+	case <-tf.ctx.Done():
+		return tf.ctx.Err()
+	case <-tf.done:
+	case <-t.C:
+	}
+
+	return nil
+}
+
+func (tf *TranscodingFile) Read(p []byte) (int, error) {
+	if err := tf.waitStarted(); err != nil {
+		return 0, err
+	}
+
+	for {
+		// checked before reading so data written just before the job finished is not missed
+		done, err := tf.finished()
+
+		n, rerr := tf.f.Read(p)
+		if n > 0 || !errors.Is(rerr, io.EOF) {
+			return n, rerr
+		}
+
+		if done {
+			if err != nil {
+				return 0, err
+			}
+
+			return 0, io.EOF
+		}
+
+		if err := tf.waitProgress(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Seek only supports io.SeekStart
+//
+// It never waits for the job: seeking past what the job has written so far fails
+// with ErrSeekAheadOfTranscode so the caller can decide how to wait.
+func (tf *TranscodingFile) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("transcoding file only supports seeking from the start")
+	}
+
+	select {
+	case <-tf.started:
+	default:
+		return 0, ErrSeekAheadOfTranscode
+	}
+
+	if err := tf.waitStarted(); err != nil {
+		return 0, err
+	}
+
+	done, err := tf.finished()
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := tf.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if !done && offset > info.Size() {
+		return 0, ErrSeekAheadOfTranscode
+	}
+
+	return tf.f.Seek(offset, io.SeekStart)
+}
+
+// Close stops the job if it is still running
+func (tf *TranscodingFile) Close() error {
+	tf.cancel()
+
+	// a job that has not started yet must not open the file after it is closed
+	tf.startOnce.Do(func() {
+		close(tf.started)
+	})
+
+	if tf.f == nil {
+		return nil
+	}
+
+	return tf.f.Close()
+}
@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/josephcopenhaver/melody-bot/internal/service"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTranscodingFile(t *testing.T) {
+	Convey("a transcoding file is read while it is written", t, func() {
+		filePath := filepath.Join(t.TempDir(), "audio.s16le")
+		f, err := os.Create(filePath)
+		So(err, ShouldBeNil)
+		defer f.Close()
+
+		tf, _ := service.NewTranscodingFile(context.Background())
+		defer tf.Close()
+
+		So(tf.Start(filePath), ShouldBeNil)
+
+		_, err = f.Write([]byte("abc"))
+		So(err, ShouldBeNil)
+
+		buf := make([]byte, 8)
+		n, err := tf.Read(buf)
+		So(err, ShouldBeNil)
+		So(string(buf[:n]), ShouldEqual, "abc")
+
+		// a reader that caught up waits for more instead of ending
+		readDone := make(chan string)
+		go func() {
+			b, _ := io.ReadAll(tf)
+			readDone <- string(b)
+		}()
+
+		select {
+		case <-readDone:
+			So("read ended before the transcode finished", ShouldBeEmpty)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		_, err = f.Write([]byte("def"))
+		So(err, ShouldBeNil)
+		tf.Finish(nil)
+
+		So(<-readDone, ShouldEqual, "def")
+	})
+
+	Convey("a seek past the data written so far fails until the transcode reaches the offset", t, func() {
+		filePath := filepath.Join(t.TempDir(), "audio.s16le")
+		So(os.WriteFile(filePath, []byte("ab"), 0o644), ShouldBeNil)
+
+		tf, _ := service.NewTranscodingFile(context.Background())
+		defer tf.Close()
+
+		_, err := tf.Seek(0, io.SeekStart)
+		So(err, ShouldEqual, service.ErrSeekAheadOfTranscode)
+
+		So(tf.Start(filePath), ShouldBeNil)
+
+		_, err = tf.Seek(4, io.SeekStart)
+		So(err, ShouldEqual, service.ErrSeekAheadOfTranscode)
+
+		So(os.WriteFile(filePath, []byte("abcdef"), 0o644), ShouldBeNil)
+
+		_, err = tf.Seek(4, io.SeekStart)
+		So(err, ShouldBeNil)
+
+		tf.Finish(nil)
+
+		b, err := io.ReadAll(tf)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "ef")
+
+		// any offset can be sought once the transcode is done
+		_, err = tf.Seek(0, io.SeekStart)
+		So(err, ShouldBeNil)
+
+		_, err = tf.Seek(10, io.SeekStart)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("a failed transcode fails the reader", t, func() {
+		errTranscode := errors.New("transcode failed")
+
+		tf, _ := service.NewTranscodingFile(context.Background())
+		defer tf.Close()
+
+		tf.Finish(errTranscode)
+
+		_, err := tf.Read(make([]byte, 8))
+		So(err, ShouldEqual, errTranscode)
+	})
+
+	Convey("closing the file cancels the transcode context", t, func() {
+		tf, ctx := service.NewTranscodingFile(context.Background())
+		So(tf.Close(), ShouldBeNil)
+		So(ctx.Err(), ShouldNotBeNil)
+	})
+
+	Convey("a prefetch job completes while a track is still playing on the only worker", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tm := service.NewTranscodeManager()
+		So(tm.Configure(1, 8), ShouldBeNil)
+		tm.Start(ctx)
+
+		filePath := filepath.Join(t.TempDir(), "audio.s16le")
+
+		tf, jobCtx := service.NewTranscodingFile(ctx)
+		defer tf.Close()
+
+		So(tm.Enqueue(jobCtx, service.TranscodeRequest{
+			GuildID:  "guild",
+			Key:      "playing",
+			Priority: service.TranscodePriorityNowPlaying,
+			Run: func(context.Context) {
+				f, err := os.Create(filePath)
+				if err != nil {
+					tf.Finish(err)
+					return
+				}
+				defer f.Close()
+
+				if err := tf.Start(filePath); err != nil {
+					tf.Finish(err)
+					return
+				}
+
+				_, err = f.Write(make([]byte, 1024))
+				tf.Finish(err)
+			},
+		}), ShouldBeNil)
+
+		// playback has only just begun
+		_, err := io.ReadFull(tf, make([]byte, 16))
+		So(err, ShouldBeNil)
+
+		prefetched := make(chan struct{})
+		So(tm.Enqueue(ctx, service.TranscodeRequest{
+			GuildID:  "guild",
+			Key:      "next",
+			Priority: service.TranscodePriorityNextUp,
+			Run: func(context.Context) {
+				close(prefetched)
+			},
+		}), ShouldBeNil)
+
+		select {
+		case <-prefetched:
+		case <-time.After(5 * time.Second):
+			So("the prefetch job waited on playback", ShouldBeEmpty)
+		}
+
+		// the rest of the track is still there to play
+		b, err := io.ReadAll(tf)
+		So(err, ShouldBeNil)
+		So(len(b), ShouldEqual, 1024-16)
+	})
+}
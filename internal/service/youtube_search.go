@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const youtubeSearchURL = "https://www.youtube.com/results"
+
+// youtubeSearchVideosOnly is the search filter that excludes channels, playlists, and shorts shelves
+const youtubeSearchVideosOnly = "EgIQAQ=="
+
+var ErrYoutubeSearchPageChanged = errors.New("youtube search page has an unexpected format")
+
+// YoutubeSearcher searches youtube videos by reading the initial data of the search results page
+type YoutubeSearcher struct {
+	client    *http.Client
+	searchURL string
+}
+
+type YoutubeSearcherOption func(*YoutubeSearcher)
+
+// YoutubeSearchURLOption replaces the url of the search results page
+func YoutubeSearchURLOption(u string) YoutubeSearcherOption {
+	return func(ys *YoutubeSearcher) {
+		ys.searchURL = u
+	}
+}
+
+func NewYoutubeSearcher(options ...YoutubeSearcherOption) *YoutubeSearcher {
+	ys := &YoutubeSearcher{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		searchURL: youtubeSearchURL,
+	}
+
+	for _, f := range options {
+		f(ys)
+	}
+
+	return ys
+}
+
+func (ys *YoutubeSearcher) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	q := url.Values{}
+	q.Set("search_query", query)
+	q.Set("sp", youtubeSearchVideosOnly)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ys.searchURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	resp, err := ys.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("youtube search failed: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	const marker = "var ytInitialData = "
+	i := bytes.Index(body, []byte(marker))
+	if i == -1 {
+		return nil, ErrYoutubeSearchPageChanged
+	}
+
+	// the decoder stops after the first value, ignoring the rest of the script
+	var data any
+	if err := json.NewDecoder(bytes.NewReader(body[i+len(marker):])).Decode(&data); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrYoutubeSearchPageChanged, err)
+	}
+
+	var result []SearchResult
+	collectYoutubeVideos(data, limit, &result)
+
+	return result, nil
+}
+
+// collectYoutubeVideos walks the page data depth first appending each video found until limit is reached
+//
+// Object keys are visited in sorted order so the same page always yields the
+// same results in the same order.
+func collectYoutubeVideos(v any, limit int, result *[]SearchResult) {
+	if len(*result) >= limit {
+		return
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if vr, ok := v["videoRenderer"].(map[string]any); ok {
+			if r, ok := newYoutubeSearchResult(vr); ok {
+				*result = append(*result, r)
+			}
+			return
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			collectYoutubeVideos(v[k], limit, result)
+		}
+	case []any:
+		for _, child := range v {
+			collectYoutubeVideos(child, limit, result)
+		}
+	}
+}
+
+func newYoutubeSearchResult(vr map[string]any) (SearchResult, bool) {
+	var result SearchResult
+
+	id, _ := vr["videoId"].(string)
+	if id == "" {
+		return result, false
+	}
+
+	result.URL = "https://www.youtube.com/watch?v=" + id
+	result.Title = youtubeText(vr["title"])
+	result.Channel = youtubeText(vr["ownerText"])
+
+	// live streams have no length
+	if length, ok := vr["lengthText"].(map[string]any); ok {
+		s, _ := length["simpleText"].(string)
+		result.Duration = parseClockDuration(s)
+	}
+
+	return result, true
+}
+
+// youtubeText returns the text of a youtube text object, which is either simple or made of runs
+func youtubeText(v any) string {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return ""
+	}
+
+	if s, ok := m["simpleText"].(string); ok {
+		return s
+	}
+
+	runs, _ := m["runs"].([]any)
+
+	var sb strings.Builder
+	for _, r := range runs {
+		if rm, ok := r.(map[string]any); ok {
+			s, _ := rm["text"].(string)
+			sb.WriteString(s)
+		}
+	}
+
+	return sb.String()
+}
+
+// parseClockDuration parses [[h:]m:]s, returning zero when s is not in that form
+func parseClockDuration(s string) time.Duration {
+	var result time.Duration
+
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0
+		}
+
+		result = result*60 + time.Duration(n)*time.Second
+	}
+
+	return result
+}